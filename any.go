@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// ANY behaviours
+const (
+	anyRefused = "refused" // REFUSED or NOTIMP
+	anyHINFO   = "hinfo"   // a synthesised HINFO record, RFC 8482 section 4.2
+	anyMinimal = "minimal" // a single RRset, RFC 8482 section 4.1
+	anyFull    = "full"    // every RRset at the name
+	anyEmpty   = "empty"
+	anyError   = "error"
+)
+
+// anyProbe is how a name server answered an ANY query for the domain.
+type anyProbe struct {
+	Addr      net.IP   `json:"addr"`
+	Behaviour string   `json:"behaviour"`
+	Types     []string `json:"types,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// probeANY sends an ANY query for the domain to the name server's first
+// address.
+func probeANY(ctx context.Context, h nsHost, domain string) (anyProbe, bool) {
+	if len(h.Addrs) == 0 {
+		return anyProbe{}, false
+	}
+	probe := anyProbe{Addr: h.Addrs[0]}
+
+	m := new(dns.Msg)
+	m.SetQuestion(domain, dns.TypeANY)
+
+	r, err := exchangeContext(ctx, m, net.JoinHostPort(probe.Addr.String(), "53"))
+	if err != nil {
+		probe.Behaviour, probe.Error = anyError, err.Error()
+		return probe, true
+	}
+
+	switch r.Rcode {
+	case dns.RcodeSuccess:
+	case dns.RcodeRefused, dns.RcodeNotImplemented:
+		probe.Behaviour = anyRefused
+		return probe, true
+	default:
+		probe.Behaviour, probe.Error = anyError, dns.RcodeToString[r.Rcode]
+		return probe, true
+	}
+
+	types := make(map[string]bool)
+	for _, a := range r.Answer {
+		types[dns.TypeToString[a.Header().Rrtype]] = true
+	}
+	for t := range types {
+		probe.Types = append(probe.Types, t)
+	}
+	sort.Strings(probe.Types)
+
+	switch {
+	case len(types) == 0:
+		probe.Behaviour = anyEmpty
+	case len(types) == 1 && types["HINFO"]:
+		probe.Behaviour = anyHINFO
+	case len(types) == 1:
+		probe.Behaviour = anyMinimal
+	default:
+		probe.Behaviour = anyFull
+	}
+
+	return probe, true
+}
+
+// probeNSHostsANY probes each of the domain's name servers with an ANY query.
+func probeNSHostsANY(ctx context.Context, domainNS *DomainNS) map[string]anyProbe {
+	probes := make(map[string]anyProbe)
+	for host, h := range domainNS.NSHosts {
+		log.Println("Probing ANY handling of NS host:", host)
+		if probe, ok := probeANY(ctx, h, domainNS.Domain); ok {
+			probes[host] = probe
+		}
+	}
+	return probes
+}
+
+// compareANY reports how each name server handled the ANY query, it's a
+// survey rather than a check so never counts as errors.
+func compareANY(domainNS *DomainNS) (errors int) {
+
+	var hosts []string
+	for host := range domainNS.ANY {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	for _, host := range hosts {
+		probe := domainNS.ANY[host]
+		detail := strings.Join(probe.Types, " ")
+		if probe.Error != "" {
+			detail = probe.Error
+		}
+		domainNS.MSGs = append(domainNS.MSGs, msg{pri: LOG_INFO, check: checkANY, msg: fmt.Sprintf("NS host %s answered ANY: %s %s", host, probe.Behaviour, detail)})
+	}
+
+	return 0
+}
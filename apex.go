@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/deckarep/golang-set"
+	"github.com/miekg/dns"
+)
+
+// queryApex fetches the A and AAAA records at the zone apex from the zone's
+// name server.
+func queryApex(ctx context.Context, domain, nameServer string) (addrs []net.IP, err error) {
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		r, err := query(ctx, domain, nameServer, qtype)
+		if err != nil {
+			return nil, err
+		}
+
+		if r.Rcode != dns.RcodeSuccess {
+			return nil, newDomainError(rcodeClass(r.Rcode), "Bad response for %s records for domain:%s", dns.TypeToString[qtype], domain)
+		}
+
+		for _, a := range r.Answer {
+			switch rr := a.(type) {
+			case *dns.A:
+				addrs = append(addrs, rr.A)
+			case *dns.AAAA:
+				addrs = append(addrs, rr.AAAA)
+			}
+		}
+	}
+
+	return
+}
+
+func compareApex(allowedAddrs mapset.Set, domainNS *DomainNS) (errors int) {
+
+	if len(domainNS.ApexAddrs) == 0 {
+		domainNS.MSGs = append(domainNS.MSGs, msg{pri: LOG_ERR, check: checkApex, msg: "Zone apex has no A or AAAA records"})
+		return 1
+	}
+
+	if allowedAddrs.Cardinality() == 0 {
+		return 0
+	}
+
+	for _, addr := range domainNS.ApexAddrs {
+		if !allowedAddrs.Contains(addr.String()) {
+			domainNS.MSGs = append(domainNS.MSGs, msg{pri: LOG_ERR, check: checkApex, msg: fmt.Sprintf("Zone apex address %s not in allowed addresses", addr)})
+			errors++
+		}
+	}
+
+	return
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// breaker tracks consecutive failures per name server, once a server reaches
+// the threshold it's skipped until the cooldown passes, so a server that's
+// down doesn't cost every domain a full set of timeouts and retries.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  map[string]int
+	openUntil map[string]time.Time
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	return &breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		failures:  make(map[string]int),
+		openUntil: make(map[string]time.Time),
+	}
+}
+
+// Allow reports whether server should be queried.
+func (b *breaker) Allow(server string) bool {
+	if b.threshold <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return time.Now().After(b.openUntil[server])
+}
+
+// Success resets server's consecutive failures.
+func (b *breaker) Success(server string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.failures, server)
+}
+
+// Failure records a failure, skipping server for the cooldown if it has
+// reached the threshold. After the cooldown, a single further failure skips
+// it again.
+func (b *breaker) Failure(server string) {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures[server]++
+	if b.failures[server] >= b.threshold {
+		b.openUntil[server] = time.Now().Add(b.cooldown)
+	}
+}
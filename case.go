@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/miekg/dns"
+)
+
+// caseEcho is whether a name server echoed the case of a query's name, see
+// draft-vixie-dnsext-dns0x20.
+type caseEcho struct {
+	Addr      net.IP `json:"addr"`
+	Sent      string `json:"sent"`
+	Received  string `json:"received,omitempty"`
+	Preserved bool   `json:"preserved"`
+	Error     string `json:"error,omitempty"`
+}
+
+var (
+	caseMu    sync.Mutex
+	caseCache = make(map[string]caseEcho)
+)
+
+// randomCase returns name with the case of each letter randomised, with at
+// least one letter upper case.
+func randomCase(name string) string {
+	b := []byte(strings.ToLower(name))
+	changed := false
+	for i, c := range b {
+		if c >= 'a' && c <= 'z' && rand.Intn(2) == 0 {
+			b[i] = c - 'a' + 'A'
+			changed = true
+		}
+	}
+	if !changed {
+		for i, c := range b {
+			if c >= 'a' && c <= 'z' {
+				b[i] = c - 'a' + 'A'
+				break
+			}
+		}
+	}
+	return string(b)
+}
+
+// checkCaseEcho queries the name server's first address with a randomly cased
+// name. Whether case is preserved doesn't depend on the domain, so results are
+// cached per name server.
+func checkCaseEcho(ctx context.Context, host string, h nsHost, domain string) (caseEcho, bool) {
+	caseMu.Lock()
+	cached, ok := caseCache[host]
+	caseMu.Unlock()
+	if ok {
+		return cached, true
+	}
+
+	if len(h.Addrs) == 0 {
+		return caseEcho{}, false
+	}
+
+	echo := caseEcho{Addr: h.Addrs[0], Sent: randomCase(domain)}
+
+	m := new(dns.Msg)
+	m.SetQuestion(echo.Sent, dns.TypeSOA)
+
+	r, err := exchangeContext(ctx, m, net.JoinHostPort(echo.Addr.String(), "53"))
+	switch {
+	case err != nil:
+		echo.Error = err.Error()
+	case len(r.Question) == 0:
+		echo.Error = "No question in response"
+	default:
+		echo.Received = r.Question[0].Name
+		echo.Preserved = echo.Received == echo.Sent
+	}
+
+	// Queries cut short by the domain's deadline say nothing about the host
+	if ctx.Err() != nil {
+		return echo, true
+	}
+
+	caseMu.Lock()
+	caseCache[host] = echo
+	caseMu.Unlock()
+
+	return echo, true
+}
+
+// caseEchoes checks each of the domain's name servers echo the query's case.
+func caseEchoes(ctx context.Context, domainNS *DomainNS) map[string]caseEcho {
+	echoes := make(map[string]caseEcho)
+	for host, h := range domainNS.NSHosts {
+		log.Println("Checking 0x20 case echo of NS host:", host)
+		if echo, ok := checkCaseEcho(ctx, host, h, domainNS.Domain); ok {
+			echoes[host] = echo
+		}
+	}
+	return echoes
+}
+
+// compareCaseEchoes reports name servers that normalised the query's case,
+// they're only informational so never count as errors.
+func compareCaseEchoes(domainNS *DomainNS) (errors int) {
+
+	var hosts []string
+	for host := range domainNS.CaseEchoes {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	for _, host := range hosts {
+		echo := domainNS.CaseEchoes[host]
+		switch {
+		case echo.Error != "":
+			domainNS.MSGs = append(domainNS.MSGs, msg{pri: LOG_INFO, check: check0x20, msg: fmt.Sprintf("NS host %s 0x20 check failed: %s", host, echo.Error)})
+		case !echo.Preserved:
+			domainNS.MSGs = append(domainNS.MSGs, msg{pri: LOG_INFO, check: check0x20, msg: fmt.Sprintf("NS host %s doesn't preserve query case for 0x20, sent %s received %s", host, echo.Sent, echo.Received)})
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/deckarep/golang-set"
+)
+
+// nsChange is an entry in the --change-log, a change to a domain's registrar
+// or zone NS records seen by watch mode.
+type nsChange struct {
+	Time   time.Time `json:"time"`
+	Domain string    `json:"domain"`
+	// Records is registrar or zone
+	Records string   `json:"records"`
+	Before  []string `json:"before"`
+	After   []string `json:"after"`
+	// Serving and NotServing are the servers that were and weren't serving
+	// the new records when the change was seen, hinting at where the change
+	// was made and how far it had propagated. For registrar records these are
+	// the parent zone's servers, for zone records the zone's servers before
+	// and after the change.
+	Serving    []string `json:"serving,omitempty"`
+	NotServing []string `json:"not_serving,omitempty"`
+}
+
+// logChange appends the change to the --change-log, as a line of JSON.
+func logChange(change nsChange) error {
+	f, err := os.OpenFile(*argsChangeLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	if err := json.NewEncoder(f).Encode(change); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// newNSChange records the change to the domain's records, checking which
+// servers are serving the new records.
+func newNSChange(domain, records string, before, after mapset.Set) nsChange {
+	change := nsChange{
+		Time:    time.Now(),
+		Domain:  domain,
+		Records: records,
+		Before:  sortedSet(before),
+		After:   sortedSet(after),
+	}
+
+	var servers []string
+	checkNS := records == "registrar"
+	if checkNS {
+		// The parent is found as when the domain was checked, checking each
+		// of its servers if they can be looked up, else the cached one
+		parent, parentNS, err := parentServer(context.Background(), domain)
+		if err != nil {
+			return change
+		}
+		servers = []string{parentNS}
+		if parentNSs, err := lookupNS(context.Background(), parent); err == nil && len(parentNSs) > 0 {
+			servers = parentNSs
+		}
+	} else {
+		servers = sortedSet(before.Union(after))
+	}
+
+	for _, server := range servers {
+		set, _, err := queryNS(context.Background(), domain, server, checkNS)
+		if err == nil && set.Equal(after) {
+			change.Serving = append(change.Serving, server)
+		} else {
+			change.NotServing = append(change.NotServing, server)
+		}
+	}
+
+	return change
+}
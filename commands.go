@@ -0,0 +1,335 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/deckarep/golang-set"
+	"github.com/droundy/goopt"
+	"github.com/miekg/dns"
+	"golang.org/x/term"
+)
+
+// command is a subcommand, given as the first argument.
+type command struct {
+	name  string
+	args  string
+	help  string
+	flags []string // options specific to the command
+}
+
+// Options shared by every command, such as how queries are sent
+var sharedFlags = []string{"--timeout", "--domain-deadline", "--retry", "--tsig-key", "--tsig-server", "--source-ip", "--proxy", "--no-system-resolver", "--mock-zone", "--breaker-threshold", "--breaker-cooldown", "--negative-cache-ttl"}
+
+var commands = []command{
+	{
+		name:  "check",
+		help:  "Check the domains' name servers, the default when no command is given",
+		flags: []string{"--file", "--source", "--credentials", "--sheet", "--column", "--header", "--nameserver", "--strict-ns", "--channel-buffer", "--workers", "--shuffle", "--zone-warnings", "--check-expiry", "--expiry-window", "--check-locks", "--registrars", "--txt-policy", "--check-apex", "--apex-allow", "--check-ns-hosts", "--dnsbl", "--check-reachability", "--fingerprint", "--check-0x20", "--probe-any", "--propagation", "--resolver", "--compare-type", "--check-referral", "--check-wildcard", "--check-soa", "--soa-range", "--check-size", "--max-udp-size", "--check-transfer", "--tui", "--watch", "--change-log", "--remote", "--remote-all", "--remote-token", "--upload", "--mail-to", "--mail-from", "--smtp", "--smtp-tls", "--smtp-user", "--schedule", "--overrides", "--suppress", "--exit-code", "--informational", "--risk-weight", "--output", "--stream"},
+	},
+	{
+		name:  "serve",
+		args:  "[address]",
+		help:  "Check domains for remote coordinators and batch jobs, on the address or --worker-listen",
+		flags: []string{"--worker-listen", "--grpc-listen", "--remote-token", "--nameserver", "--strict-ns", "--workers", "--zone-warnings", "--check-expiry", "--expiry-window", "--check-locks", "--registrars", "--txt-policy", "--check-apex", "--apex-allow", "--check-ns-hosts", "--dnsbl", "--check-reachability", "--fingerprint", "--check-0x20", "--probe-any", "--propagation", "--resolver", "--compare-type", "--check-referral", "--check-wildcard", "--check-soa", "--soa-range", "--check-size", "--max-udp-size", "--check-transfer", "--overrides", "--suppress", "--informational", "--risk-weight"},
+	},
+	{
+		name: "diff",
+		args: "old.json new.json",
+		help: "Show NS records and errors that changed between two JSON reports, exiting with 1 if any did",
+	},
+	{
+		name:  "report",
+		args:  "report.json|directory...",
+		help:  "Show trends across stored JSON reports, the error rate of each run, domains that flapped and mean time to fix",
+		flags: []string{"--since", "--output"},
+	},
+	{
+		name:  "bench",
+		args:  "host...",
+		help:  "Measure each name server's query latency",
+		flags: []string{"--bench-queries", "--probe-zone", "--exit-code"},
+	},
+	{
+		name:  "ns-health",
+		args:  "host...",
+		help:  "Check name servers directly, independent of any domains",
+		flags: []string{"--probe-zone", "--exit-code"},
+	},
+	{
+		name: "schema",
+		help: "Print the JSON Schema of JSON reports, including their schema_version",
+	},
+	{
+		name: "completion",
+		help: "Print a bash completion script, eg source <(nsaudit completion)",
+	},
+	{
+		name: "help",
+		args: "[command]",
+		help: "Show a command's usage and options",
+	},
+}
+
+// findCommand returns the named command.
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// checkFlags returns an error for the first option in args that doesn't apply
+// to the command, as goopt accepts every option whatever the command.
+func checkFlags(cmd command, args []string) error {
+	allowed := map[string]bool{"--help": true, "--list-options": true}
+	for _, flag := range append(append([]string{}, cmd.flags...), sharedFlags...) {
+		allowed[flag] = true
+	}
+	short := shortFlags()
+
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			continue
+		}
+
+		flag := strings.SplitN(arg, "=", 2)[0]
+		if !strings.HasPrefix(arg, "--") {
+			// A short option, possibly followed by its value
+			flag = short[arg[:2]]
+		}
+		if flag != "" && !allowed[flag] {
+			return errors.New(fmt.Sprintf("Option %s doesn't apply to the %s command, see nsaudit help %s", flag, cmd.name, cmd.name))
+		}
+	}
+	return nil
+}
+
+// shortFlags maps each short option to its long option, from goopt's usage
+// lines such as "  -n              --nameserver=".
+func shortFlags() map[string]string {
+	short := make(map[string]string)
+	for _, line := range strings.Split(goopt.Usage(), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || len(fields[0]) != 2 || fields[0][0] != '-' || !strings.HasPrefix(fields[1], "--") {
+			continue
+		}
+		short[fields[0]] = strings.SplitN(fields[1], "=", 2)[0]
+	}
+	return short
+}
+
+// commandNames returns each command's name, which are offered alongside the
+// options when completing.
+func commandNames() []string {
+	var names []string
+	for _, cmd := range commands {
+		names = append(names, cmd.name)
+	}
+	return names
+}
+
+// runHelp prints the usage of the command, or of every command.
+func runHelp(args []string) {
+	if len(args) == 0 {
+		fmt.Println("Usage: nsaudit [command] [options]")
+		fmt.Println()
+		fmt.Println("Commands:")
+		for _, cmd := range commands {
+			fmt.Printf("  %-12s %s\n", cmd.name, cmd.help)
+		}
+		fmt.Println()
+		fmt.Println("Use nsaudit help <command> for a command's options, or nsaudit --help for all options.")
+		return
+	}
+
+	cmd, ok := findCommand(args[0])
+	if !ok {
+		log.Fatalf("Unknown command %s, see nsaudit help", args[0])
+	}
+
+	fmt.Printf("Usage: nsaudit %s [options] %s\n\n%s\n", cmd.name, cmd.args, cmd.help)
+
+	// Pick the command's lines out of the full usage, so help is only
+	// written once
+	flags := append(append([]string{}, cmd.flags...), sharedFlags...)
+	fmt.Println()
+	fmt.Println("Options:")
+	for _, line := range strings.Split(goopt.Usage(), "\n") {
+		for _, flag := range flags {
+			if strings.Contains(line, " "+flag+"=") || strings.Contains(line, " "+flag+" ") {
+				fmt.Println(line)
+				break
+			}
+		}
+	}
+}
+
+// printCompletion prints a bash completion script, completing the commands and
+// options goopt lists with --list-options.
+func printCompletion() {
+	fmt.Print(`_nsaudit() {
+	local cur="${COMP_WORDS[COMP_CWORD]}"
+	COMPREPLY=($(compgen -W "$(${COMP_WORDS[0]} --list-options 2>/dev/null)" -- "$cur"))
+	if [ ${#COMPREPLY[@]} -eq 0 ]; then
+		COMPREPLY=($(compgen -f -- "$cur"))
+	fi
+}
+complete -F _nsaudit nsaudit
+`)
+}
+
+// runDiff prints the changes to each domain's NS records and error between two
+// JSON reports, such as those written by --upload, returning whether there were
+// any.
+func runDiff(oldPath, newPath string) (changed bool, err error) {
+
+	oldReport, err := readReport(oldPath)
+	if err != nil {
+		return false, err
+	}
+	newReport, err := readReport(newPath)
+	if err != nil {
+		return false, err
+	}
+
+	colour := term.IsTerminal(int(os.Stdout.Fd()))
+
+	old := make(map[string]jsonDomain)
+	for _, j := range oldReport.Results {
+		old[j.Domain] = j
+	}
+
+	seen := mapset.NewSet()
+	for _, j := range newReport.Results {
+		seen.Add(j.Domain)
+
+		prev, ok := old[j.Domain]
+		if !ok {
+			fmt.Printf("%s added\n", j.Domain)
+			changed = true
+			continue
+		}
+
+		if diff := nsDiff(setFromStrings(prev.RegistrarNS), setFromStrings(j.RegistrarNS), colour); diff != "" {
+			fmt.Printf("%s registrar NS changed: %s\n", j.Domain, diff)
+			changed = true
+		}
+		if diff := nsDiff(setFromStrings(prev.ZoneNS), setFromStrings(j.ZoneNS), colour); diff != "" {
+			fmt.Printf("%s zone NS changed: %s\n", j.Domain, diff)
+			changed = true
+		}
+
+		switch {
+		case prev.Error == nil && j.Error != nil:
+			fmt.Printf("%s now failing: [%s] %s\n", j.Domain, j.Error.Class, j.Error.Message)
+			changed = true
+		case prev.Error != nil && j.Error == nil:
+			fmt.Printf("%s no longer failing, was: [%s] %s\n", j.Domain, prev.Error.Class, prev.Error.Message)
+			changed = true
+		}
+	}
+
+	for _, j := range oldReport.Results {
+		if !seen.Contains(j.Domain) {
+			fmt.Printf("%s removed\n", j.Domain)
+			changed = true
+		}
+	}
+
+	return
+}
+
+func readReport(path string) (*jsonReport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var report jsonReport
+	if err := json.NewDecoder(f).Decode(&report); err != nil {
+		return nil, errors.New(fmt.Sprintf("Could not read report %s: %s", path, err))
+	}
+	if report.SchemaVersion > schemaVersion {
+		return nil, errors.New(fmt.Sprintf("Report %s uses schema version %d, this nsaudit only supports up to %d", path, report.SchemaVersion, schemaVersion))
+	}
+	upgradeReport(&report)
+	return &report, nil
+}
+
+// runBench queries each address of each name server --bench-queries times over
+// UDP, printing the minimum, average and maximum latency. It returns the
+// priority of the most severe failure.
+func runBench(hosts []string) (worst int) {
+
+	worst = LOG_DIFF
+
+	probe := dns.Fqdn(*argsProbe)
+	if *argsProbe == "" {
+		probe = "."
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion(probe, dns.TypeSOA)
+
+	for _, host := range hosts {
+		host = strings.TrimRight(host, ".") + "."
+
+		addrs, err := resolver.LookupIPAddr(context.Background(), host)
+		if err != nil {
+			fmt.Printf("%s CRIT: Could not resolve: %s\n", host, err)
+			worst = LOG_CRIT
+			continue
+		}
+
+		for _, addr := range addrs {
+			var (
+				min, max, total time.Duration
+				ok, failed      int
+			)
+			for i := 0; i < *argsBenchN; i++ {
+				_, rtt, err := exchangeNet(m, net.JoinHostPort(addr.IP.String(), "53"), "udp")
+				if err != nil {
+					failed++
+					continue
+				}
+				if ok == 0 || rtt < min {
+					min = rtt
+				}
+				if rtt > max {
+					max = rtt
+				}
+				total += rtt
+				ok++
+			}
+
+			if ok == 0 {
+				fmt.Printf("%s %s all %d queries failed\n", host, addr.IP, failed)
+				if worst < LOG_ERR {
+					worst = LOG_ERR
+				}
+				continue
+			}
+			avg := total / time.Duration(ok)
+			fmt.Printf("%s %s min %s avg %s max %s, %d/%d failed\n", host, addr.IP, min.Round(time.Microsecond), avg.Round(time.Microsecond), max.Round(time.Microsecond), failed, *argsBenchN)
+			if failed > 0 && worst < LOG_WARNING {
+				worst = LOG_WARNING
+			}
+		}
+	}
+
+	return
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// dnsblListing is a name server address listed on a DNS blocklist.
+type dnsblListing struct {
+	Host   string   `json:"host"`
+	IP     net.IP   `json:"ip"`
+	Zone   string   `json:"zone"`
+	Result []string `json:"result"`
+}
+
+var (
+	dnsblMu    sync.Mutex
+	dnsblCache = make(map[string][]string)
+)
+
+// dnsblName returns the name to look up ip on the blocklist zone, the
+// address's octets (or IPv6 nibbles) reversed and prefixed to the zone.
+func dnsblName(ip net.IP, zone string) string {
+	var labels []string
+	if ip4 := ip.To4(); ip4 != nil {
+		for i := len(ip4) - 1; i >= 0; i-- {
+			labels = append(labels, fmt.Sprintf("%d", ip4[i]))
+		}
+	} else {
+		ip16 := ip.To16()
+		for i := len(ip16) - 1; i >= 0; i-- {
+			labels = append(labels, fmt.Sprintf("%x", ip16[i]&0xf), fmt.Sprintf("%x", ip16[i]>>4))
+		}
+	}
+	return strings.Join(labels, ".") + "." + strings.TrimRight(zone, ".") + "."
+}
+
+// resetDNSBLCache forgets the cached listings, see resetHostCaches.
+func resetDNSBLCache() {
+	dnsblMu.Lock()
+	dnsblCache = make(map[string][]string)
+	dnsblMu.Unlock()
+}
+
+// queryDNSBL returns the addresses the blocklist returned for ip, which are
+// empty if ip isn't listed. Addresses are shared by many domains so results
+// are cached for the run, failed lookups aren't cached.
+func queryDNSBL(ctx context.Context, ip net.IP, zone string) ([]string, error) {
+	name := dnsblName(ip, zone)
+
+	dnsblMu.Lock()
+	cached, ok := dnsblCache[name]
+	dnsblMu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	result, err := resolver.LookupHost(ctx, name)
+	if dnsErr, ok := err.(*net.DNSError); ok && dnsErr.IsNotFound {
+		result, err = nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dnsblMu.Lock()
+	dnsblCache[name] = result
+	dnsblMu.Unlock()
+
+	return result, nil
+}
+
+// lookupDNSBL looks up each name server address on each blocklist zone.
+func lookupDNSBL(ctx context.Context, hosts map[string]nsHost, zones []string) (listings []dnsblListing) {
+
+	var names []string
+	for host := range hosts {
+		names = append(names, host)
+	}
+	sort.Strings(names)
+
+	for _, host := range names {
+		for _, ip := range hosts[host].Addrs {
+			for _, zone := range zones {
+				result, err := queryDNSBL(ctx, ip, zone)
+				if err != nil {
+					log.Printf("Error looking up %s on %s: %s", ip, zone, err)
+					continue
+				}
+				if len(result) > 0 {
+					listings = append(listings, dnsblListing{Host: host, IP: ip, Zone: zone, Result: result})
+				}
+			}
+		}
+	}
+
+	return
+}
+
+func compareDNSBL(domainNS *DomainNS) (errors int) {
+	for _, listing := range domainNS.DNSBL {
+		domainNS.MSGs = append(domainNS.MSGs, msg{pri: LOG_ERR, check: checkDNSBL, msg: fmt.Sprintf("NS host %s address %s listed on %s: %s", listing.Host, listing.IP, listing.Zone, strings.Join(listing.Result, ", "))})
+		errors++
+	}
+	return
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+
+	"github.com/miekg/dns"
+)
+
+// ErrorClass categorises why a domain couldn't be checked, so results can be
+// aggregated by failure type.
+type ErrorClass int
+
+const (
+	ErrUnknown ErrorClass = iota
+	ErrTimeout
+	ErrNXDomain
+	ErrServFail
+	ErrRefused
+	ErrUnreachable
+	ErrBadDelegation
+	ErrUnavailable
+	ErrInvalidTLD
+)
+
+var errorClassNames = []string{
+	ErrUnknown:       "unknown",
+	ErrTimeout:       "timeout",
+	ErrNXDomain:      "nxdomain",
+	ErrServFail:      "servfail",
+	ErrRefused:       "refused",
+	ErrUnreachable:   "unreachable",
+	ErrBadDelegation: "bad-delegation",
+	ErrUnavailable:   "server-unavailable",
+	ErrInvalidTLD:    "invalid-tld",
+}
+
+func (c ErrorClass) String() string {
+	if int(c) < len(errorClassNames) {
+		return errorClassNames[c]
+	}
+	return errorClassNames[ErrUnknown]
+}
+
+// DomainError is an error checking a domain along with its class.
+type DomainError struct {
+	Class ErrorClass
+	Err   error
+}
+
+func newDomainError(class ErrorClass, format string, a ...interface{}) *DomainError {
+	return &DomainError{Class: class, Err: errors.New(fmt.Sprintf(format, a...))}
+}
+
+func (e *DomainError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DomainError) Unwrap() error {
+	return e.Err
+}
+
+// classifyError wraps err in a DomainError, inspecting it to determine its
+// class unless it's already a DomainError.
+func classifyError(err error) *DomainError {
+	var domainErr *DomainError
+	if errors.As(err, &domainErr) {
+		return domainErr
+	}
+
+	class := ErrUnknown
+
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &dnsErr) && dnsErr.IsNotFound:
+		class = ErrNXDomain
+	case errors.As(err, &netErr) && netErr.Timeout():
+		class = ErrTimeout
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ENETUNREACH), errors.Is(err, syscall.EHOSTUNREACH):
+		class = ErrUnreachable
+	}
+
+	return &DomainError{Class: class, Err: err}
+}
+
+// rcodeClass returns the class of an unsuccessful response code.
+func rcodeClass(rcode int) ErrorClass {
+	switch rcode {
+	case dns.RcodeNameError:
+		return ErrNXDomain
+	case dns.RcodeServerFailure:
+		return ErrServFail
+	case dns.RcodeRefused:
+		return ErrRefused
+	}
+	return ErrUnknown
+}
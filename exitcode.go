@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// exitCodes maps the most severe message shown to the exit code, always 0
+	// unless set with --exit-code
+	exitCodes = map[int]int{LOG_INFO: 0, LOG_WARNING: 0, LOG_ERR: 0, LOG_CRIT: 0}
+
+	// informational checks are reported as LOG_INFO and not counted as errors
+	informational = make(map[string]bool)
+)
+
+// parseExitCodes overrides exitCodes with severity=code pairs, eg warn=2.
+func parseExitCodes(specs []string) error {
+	for _, spec := range specs {
+		parts := strings.SplitN(spec, "=", 2)
+		if len(parts) != 2 {
+			return errors.New(fmt.Sprintf("Invalid exit code %s, expected severity=code", spec))
+		}
+
+		code, err := strconv.Atoi(parts[1])
+		if err != nil || code < 0 || code > 125 {
+			return errors.New(fmt.Sprintf("Invalid exit code %s, must be between 0 and 125", parts[1]))
+		}
+
+		pri := -1
+		for p, name := range priNames {
+			if p != LOG_DIFF && strings.EqualFold(name, parts[0]) {
+				pri = p
+			}
+		}
+		if pri == -1 {
+			return errors.New(fmt.Sprintf("Invalid severity %s, expected info, warn, err or crit", parts[0]))
+		}
+
+		exitCodes[pri] = code
+	}
+	return nil
+}
+
+// parseInformational sets the checks to report as informational.
+func parseInformational(names []string) error {
+	for _, name := range names {
+		if !validCheck(name) {
+			return errors.New(fmt.Sprintf("Unknown check %s, expected one of: %s", name, strings.Join(checks, ", ")))
+		}
+		informational[name] = true
+	}
+	return nil
+}
+
+// markInformational downgrades messages from informational checks to
+// LOG_INFO, returning how many were downgraded.
+func markInformational(domainNS *DomainNS) (n int) {
+	for i := range domainNS.MSGs {
+		if informational[domainNS.MSGs[i].check] && domainNS.MSGs[i].pri != LOG_INFO {
+			domainNS.MSGs[i].pri = LOG_INFO
+			n++
+		}
+	}
+	return
+}
+
+// worstPri returns the most severe message's priority, or LOG_DIFF if the
+// domain has no messages. Warnings are only shown, and so only counted, with
+// --zone-warnings.
+func worstPri(domainNS *DomainNS) (pri int) {
+	pri = LOG_DIFF
+	for _, msg := range domainNS.MSGs {
+		if msg.pri == LOG_WARNING && !*argsZ {
+			continue
+		}
+		if msg.pri > pri {
+			pri = msg.pri
+		}
+	}
+	return
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseExitCodes(t *testing.T) {
+	tests := []struct {
+		specs   []string
+		want    map[int]int
+		wantErr bool
+	}{
+		{nil, map[int]int{LOG_INFO: 0, LOG_WARNING: 0, LOG_ERR: 0, LOG_CRIT: 0}, false},
+		{[]string{"warn=1", "ERR=2", "crit=125"}, map[int]int{LOG_INFO: 0, LOG_WARNING: 1, LOG_ERR: 2, LOG_CRIT: 125}, false},
+		{[]string{"info=0"}, map[int]int{LOG_INFO: 0, LOG_WARNING: 0, LOG_ERR: 0, LOG_CRIT: 0}, false},
+		{[]string{"warn"}, nil, true},
+		{[]string{"warn=x"}, nil, true},
+		{[]string{"warn=-1"}, nil, true},
+		{[]string{"warn=126"}, nil, true},
+		{[]string{"diff=1"}, nil, true},
+		{[]string{"fatal=1"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		exitCodes = map[int]int{LOG_INFO: 0, LOG_WARNING: 0, LOG_ERR: 0, LOG_CRIT: 0}
+
+		err := parseExitCodes(tt.specs)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseExitCodes(%q) error %v, want error %t", tt.specs, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(exitCodes, tt.want) {
+			t.Errorf("parseExitCodes(%q) got %v, want %v", tt.specs, exitCodes, tt.want)
+		}
+	}
+}
+
+func TestWorstPri(t *testing.T) {
+	defer func(z bool) { *argsZ = z }(*argsZ)
+
+	tests := []struct {
+		pris []int
+		z    bool
+		want int
+	}{
+		{nil, false, LOG_DIFF},
+		{[]int{LOG_INFO}, false, LOG_INFO},
+		{[]int{LOG_INFO, LOG_ERR, LOG_WARNING}, false, LOG_ERR},
+		{[]int{LOG_CRIT, LOG_ERR}, false, LOG_CRIT},
+		// Warnings are hidden without --zone-warnings
+		{[]int{LOG_INFO, LOG_WARNING}, false, LOG_INFO},
+		{[]int{LOG_WARNING}, false, LOG_DIFF},
+		{[]int{LOG_INFO, LOG_WARNING}, true, LOG_WARNING},
+	}
+
+	for _, tt := range tests {
+		*argsZ = tt.z
+
+		domainNS := DomainNS{}
+		for _, pri := range tt.pris {
+			domainNS.MSGs = append(domainNS.MSGs, msg{pri: pri, check: checkZone})
+		}
+		if got := worstPri(&domainNS); got != tt.want {
+			t.Errorf("worstPri(%v) with --zone-warnings %t got %d, want %d", tt.pris, tt.z, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"sync"
+
+	"github.com/miekg/dns"
+)
+
+// fingerprint identifies the server software and instance that answered for
+// a name server, either may be empty as many servers don't say.
+type fingerprint struct {
+	Addr    net.IP `json:"addr"`
+	NSID    string `json:"nsid,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+var (
+	fingerprintMu    sync.Mutex
+	fingerprintCache = make(map[string]fingerprint)
+)
+
+// queryNSID returns the NSID (RFC 5001) the server includes in its response
+// to an SOA query for domain.
+func queryNSID(ctx context.Context, domain, address string) (string, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(domain, dns.TypeSOA)
+	m.SetEdns0(dns.DefaultMsgSize, false)
+	opt := m.IsEdns0()
+	opt.Option = append(opt.Option, &dns.EDNS0_NSID{Code: dns.EDNS0NSID})
+
+	r, err := exchangeContext(ctx, m, address)
+	if err != nil {
+		return "", err
+	}
+
+	if opt := r.IsEdns0(); opt != nil {
+		for _, o := range opt.Option {
+			if nsid, ok := o.(*dns.EDNS0_NSID); ok {
+				// The NSID is hex encoded, but is usually printable
+				if b, err := hex.DecodeString(nsid.Nsid); err == nil {
+					return string(b), nil
+				}
+				return nsid.Nsid, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// fingerprintNSHost queries the name server's first address for its NSID and
+// version.bind. Neither depend on the domain, so results are cached per name
+// server.
+func fingerprintNSHost(ctx context.Context, host string, h nsHost, domain string) (fingerprint, bool) {
+	fingerprintMu.Lock()
+	cached, ok := fingerprintCache[host]
+	fingerprintMu.Unlock()
+	if ok {
+		return cached, true
+	}
+
+	if len(h.Addrs) == 0 {
+		return fingerprint{}, false
+	}
+
+	fp := fingerprint{Addr: h.Addrs[0]}
+	address := net.JoinHostPort(fp.Addr.String(), "53")
+
+	var err error
+	if fp.NSID, err = queryNSID(ctx, domain, address); err != nil {
+		log.Printf("Error fetching NSID from %s: %s", host, err)
+	}
+	if fp.Version, err = versionBind(ctx, address); err != nil {
+		log.Printf("Error fetching version.bind from %s: %s", host, err)
+	}
+
+	// Queries cut short by the domain's deadline say nothing about the host
+	if ctx.Err() != nil {
+		return fp, true
+	}
+
+	fingerprintMu.Lock()
+	fingerprintCache[host] = fp
+	fingerprintMu.Unlock()
+
+	return fp, true
+}
+
+// fingerprintNSHosts fingerprints each of the domain's name servers.
+func fingerprintNSHosts(ctx context.Context, domainNS *DomainNS) map[string]fingerprint {
+	fingerprints := make(map[string]fingerprint)
+	for host, h := range domainNS.NSHosts {
+		log.Println("Fingerprinting NS host:", host)
+		if fp, ok := fingerprintNSHost(ctx, host, h, domainNS.Domain); ok {
+			fingerprints[host] = fp
+		}
+	}
+	return fingerprints
+}
+
+// compareFingerprints reports each name server's identifiers, they're only
+// informational so never count as errors.
+func compareFingerprints(domainNS *DomainNS) (errors int) {
+
+	var hosts []string
+	for host := range domainNS.Fingerprints {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	for _, host := range hosts {
+		fp := domainNS.Fingerprints[host]
+		domainNS.MSGs = append(domainNS.MSGs, msg{pri: LOG_INFO, check: checkFingerprint, msg: fmt.Sprintf("NS host %s (%s) NSID: %q, version.bind: %q", host, fp.Addr, fp.NSID, fp.Version)})
+	}
+
+	return 0
+}
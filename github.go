@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+// ghEscapeData escapes a workflow command's message, see
+// https://github.com/actions/toolkit/blob/main/packages/core/src/command.ts
+func ghEscapeData(s string) string {
+	s = strings.Replace(s, "%", "%25", -1)
+	s = strings.Replace(s, "\r", "%0D", -1)
+	s = strings.Replace(s, "\n", "%0A", -1)
+	return s
+}
+
+// ghEscapeProperty escapes a workflow command's property value, such as title
+func ghEscapeProperty(s string) string {
+	s = ghEscapeData(s)
+	s = strings.Replace(s, ":", "%3A", -1)
+	s = strings.Replace(s, ",", "%2C", -1)
+	return s
+}
+
+// displayGitHubMsgs prints a domain's messages as GitHub Actions workflow
+// commands, so they're shown as annotations on the run.
+func displayGitHubMsgs(w io.Writer, domainNS *DomainNS) {
+
+	title := "nsaudit " + domainNS.Domain
+	if domainNS.Vantage != "" {
+		title += " via " + domainNS.Vantage
+	}
+	title = ghEscapeProperty(title)
+
+	for _, msg := range domainNS.MSGs {
+		switch msg.pri {
+		case LOG_CRIT, LOG_ERR:
+			fmt.Fprintf(w, "::error title=%s::%s\n", title, ghEscapeData(msg.msg))
+		case LOG_WARNING:
+			if *argsZ {
+				fmt.Fprintf(w, "::warning title=%s::%s\n", title, ghEscapeData(msg.msg))
+			}
+		default:
+			// Including LOG_INFO
+			text := msg.msg
+			if msg.suppressed != "" {
+				text = fmt.Sprintf("Suppressed: %s (%s)", msg.msg, msg.suppressed)
+			}
+			fmt.Fprintf(w, "::notice title=%s::%s\n", title, ghEscapeData(text))
+		}
+	}
+}
+
+// writeGitHubSummary appends a Markdown summary of the run to the file GitHub
+// Actions provides in GITHUB_STEP_SUMMARY, it's a no-op outside of Actions.
+func writeGitHubSummary(stats *auditStats) error {
+
+	path := os.Getenv("GITHUB_STEP_SUMMARY")
+	if path == "" {
+		log.Println("GITHUB_STEP_SUMMARY not set, skipping job summary")
+		return nil
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fmt.Fprintf(f, "## nsaudit\n\n")
+	fmt.Fprintf(f, "| Domains | With Errors/Warnings | Total Errors |\n")
+	fmt.Fprintf(f, "|---|---|---|\n")
+	fmt.Fprintf(f, "| %d | %d | %d |\n\n", stats.Domains, stats.DomainsWithErrors, stats.TotalErrors)
+
+	if len(stats.ErrorClasses) > 0 {
+		fmt.Fprintf(f, "| Failure Type | Domains |\n")
+		fmt.Fprintf(f, "|---|---|\n")
+		for class := ErrUnknown; class <= ErrInvalidTLD; class++ {
+			if stats.ErrorClasses[class] > 0 {
+				fmt.Fprintf(f, "| %s | %d |\n", class, stats.ErrorClasses[class])
+			}
+		}
+		fmt.Fprintf(f, "\n")
+	}
+
+	if len(stats.Registrars) > 0 {
+		fmt.Fprintf(f, "| Registrar | Domains | With Errors/Warnings |\n")
+		fmt.Fprintf(f, "|---|---|---|\n")
+		for _, name := range stats.registrarNames() {
+			fmt.Fprintf(f, "| %s | %d | %d |\n", strings.Replace(name, "|", "\\|", -1), stats.Registrars[name].Domains, stats.Registrars[name].DomainsWithErrors)
+		}
+		fmt.Fprintf(f, "\n")
+	}
+
+	if len(stats.Failed) == 0 {
+		fmt.Fprintf(f, "All domains OK\n")
+		return nil
+	}
+
+	fmt.Fprintf(f, "| Domain | Risk Score | Severity | Message |\n")
+	fmt.Fprintf(f, "|---|---|---|---|\n")
+	for _, domainNS := range stats.Failed {
+		for _, msg := range domainNS.MSGs {
+			var severity string
+			switch msg.pri {
+			case LOG_CRIT:
+				severity = "CRIT"
+			case LOG_ERR:
+				severity = "ERR"
+			case LOG_WARNING:
+				if !*argsZ {
+					continue
+				}
+				severity = "WARN"
+			case LOG_INFO:
+				severity = "INFO"
+				if msg.suppressed != "" {
+					severity = "SUPPRESSED"
+				}
+			default:
+				severity = "UNKN"
+			}
+			fmt.Fprintf(f, "| %s | %d | %s | %s |\n", domainNS.Domain, domainNS.Score, severity, strings.Replace(msg.msg, "|", "\\|", -1))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,192 @@
+package main
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative nsauditpb/nsaudit.proto
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net"
+	"time"
+
+	"github.com/bradleyfalzon/nsaudit/nsauditpb"
+	"github.com/deckarep/golang-set"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// grpcServer implements the NSAudit gRPC service, see nsauditpb/nsaudit.proto.
+type grpcServer struct {
+	nsauditpb.UnimplementedNSAuditServer
+	requiredNS   mapset.Set
+	allowedAddrs mapset.Set
+}
+
+// serveGRPC serves the NSAudit gRPC service on addr, alongside the HTTP API.
+func serveGRPC(addr string, requiredNS, allowedAddrs mapset.Set) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	s := grpc.NewServer(grpc.StreamInterceptor(grpcAuth))
+	nsauditpb.RegisterNSAuditServer(s, &grpcServer{requiredNS: requiredNS, allowedAddrs: allowedAddrs})
+
+	log.Println("Listening for gRPC requests on:", addr)
+	return s.Serve(l)
+}
+
+// grpcAuth rejects streams without the --remote-token, if set.
+func grpcAuth(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if *argsRemoteToken != "" {
+		md, _ := metadata.FromIncomingContext(ss.Context())
+		auth := md.Get("authorization")
+		if len(auth) == 0 || auth[0] != "Bearer "+*argsRemoteToken {
+			return status.Error(codes.Unauthenticated, "Unauthorized")
+		}
+	}
+	return handler(srv, ss)
+}
+
+// Audit checks each domain, sending each result as soon as it's checked.
+func (s *grpcServer) Audit(req *nsauditpb.AuditRequest, stream nsauditpb.NSAudit_AuditServer) error {
+	log.Printf("Checking %d domains via gRPC", len(req.Domains))
+	resetHostCaches()
+
+	results := checkQueue(queueDomains(stream.Context(), req.Domains), flagOptions())
+	for domainNS := range results {
+		compareNS(s.requiredNS, s.allowedAddrs, &domainNS)
+		if err := stream.Send(newDomainResult(&domainNS)); err != nil {
+			go drain(results)
+			return err
+		}
+	}
+
+	return stream.Context().Err()
+}
+
+// Watch checks the domains every interval until the client cancels, sending
+// each domain's NS records the first time they're seen and when they change.
+func (s *grpcServer) Watch(req *nsauditpb.WatchRequest, stream nsauditpb.NSAudit_WatchServer) error {
+	interval := req.Interval.AsDuration()
+	if interval < time.Second {
+		return status.Error(codes.InvalidArgument, "Interval must be at least one second")
+	}
+
+	log.Printf("Watching %d domains every %s via gRPC", len(req.Domains), interval)
+
+	previous := make(map[string]nsSnapshot)
+	for {
+		resetHostCaches()
+
+		results := checkQueue(queueDomains(stream.Context(), req.Domains), flagOptions())
+		for domainNS := range results {
+			if domainNS.Error != nil {
+				log.Printf("Error checking domain %s: %s", domainNS.Domain, domainNS.Error)
+				continue
+			}
+
+			prev, ok := previous[domainNS.Domain]
+			previous[domainNS.Domain] = nsSnapshot{RegistrarNS: domainNS.RegistrarNS, ZoneNS: domainNS.ZoneNS}
+
+			var changes []nsChange
+			switch {
+			case !ok:
+				now := time.Now()
+				changes = append(changes,
+					nsChange{Time: now, Domain: domainNS.Domain, Records: "registrar", After: sortedSet(domainNS.RegistrarNS)},
+					nsChange{Time: now, Domain: domainNS.Domain, Records: "zone", After: sortedSet(domainNS.ZoneNS)},
+				)
+			default:
+				if !prev.RegistrarNS.Equal(domainNS.RegistrarNS) {
+					changes = append(changes, newNSChange(domainNS.Domain, "registrar", prev.RegistrarNS, domainNS.RegistrarNS))
+				}
+				if !prev.ZoneNS.Equal(domainNS.ZoneNS) {
+					changes = append(changes, newNSChange(domainNS.Domain, "zone", prev.ZoneNS, domainNS.ZoneNS))
+				}
+			}
+
+			for _, change := range changes {
+				if err := stream.Send(newNSChangePB(change)); err != nil {
+					go drain(results)
+					return err
+				}
+			}
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// queueDomains sends each domain to the returned channel for checkQueue,
+// closing it once all are sent or, if the client goes away, as soon as ctx is
+// done so no more domains are checked.
+func queueDomains(ctx context.Context, domains []string) chan string {
+	inChan := make(chan string)
+	go func() {
+		defer close(inChan)
+		for _, domain := range domains {
+			select {
+			case inChan <- domain:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return inChan
+}
+
+// drain discards checkQueue's remaining results, so its workers finish once
+// the client has gone.
+func drain(results chan DomainNS) {
+	for range results {
+	}
+}
+
+// newDomainResult returns the protobuf representation of a checked domain.
+func newDomainResult(domainNS *DomainNS) *nsauditpb.DomainResult {
+	j := newJSONDomain(domainNS)
+
+	result := &nsauditpb.DomainResult{
+		Domain:        j.Domain,
+		RegistrarNs:   j.RegistrarNS,
+		ZoneNs:        j.ZoneNS,
+		RequiredNs:    j.RequiredNS,
+		SchemaVersion: schemaVersion,
+		Score:         int32(j.Score),
+	}
+	if j.Error != nil {
+		result.Error = &nsauditpb.Error{Class: j.Error.Class, Message: j.Error.Message}
+	}
+	for _, m := range j.Messages {
+		result.Messages = append(result.Messages, &nsauditpb.Message{Severity: m.Severity, Check: m.Check, Message: m.Message, Suppressed: m.Suppressed})
+	}
+
+	if b, err := json.Marshal(j); err != nil {
+		log.Println("Error encoding result:", err)
+	} else {
+		result.Json = string(b)
+	}
+
+	return result
+}
+
+// newNSChangePB returns the protobuf representation of a change.
+func newNSChangePB(change nsChange) *nsauditpb.NSChange {
+	return &nsauditpb.NSChange{
+		Time:       timestamppb.New(change.Time),
+		Domain:     change.Domain,
+		Records:    change.Records,
+		Before:     change.Before,
+		After:      change.After,
+		Serving:    change.Serving,
+		NotServing: change.NotServing,
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestQueueDomains(t *testing.T) {
+	domains := []string{"example.com", "example.net", "example.org"}
+
+	var got []string
+	for domain := range queueDomains(context.Background(), domains) {
+		got = append(got, domain)
+	}
+	if !reflect.DeepEqual(got, domains) {
+		t.Errorf("queueDomains got %v, want %v", got, domains)
+	}
+
+	// Once the client has gone the channel is closed without sending the
+	// remaining domains
+	ctx, cancel := context.WithCancel(context.Background())
+	inChan := queueDomains(ctx, domains)
+	<-inChan
+	cancel()
+	n := 0
+	for range inChan {
+		n++
+	}
+	if n > 1 {
+		t.Errorf("queueDomains sent %d domains after cancel, want at most 1", n)
+	}
+}
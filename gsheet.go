@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const gsheetScope = "https://www.googleapis.com/auth/spreadsheets.readonly"
+
+// fetchGSheet fetches the range of cells, eg Domains!A:C, from a Google Sheets
+// spreadsheet. The sheet must be shared with the service account in
+// --credentials, or Application Default Credentials if not set.
+func fetchGSheet(spreadsheetID, cellRange string) ([][]string, error) {
+	ctx := context.Background()
+
+	var client *http.Client
+	if *argsCredentials != "" {
+		key, err := ioutil.ReadFile(*argsCredentials)
+		if err != nil {
+			return nil, err
+		}
+		creds, err := google.CredentialsFromJSON(ctx, key, gsheetScope)
+		if err != nil {
+			return nil, err
+		}
+		client = oauth2.NewClient(ctx, creds.TokenSource)
+	} else {
+		var err error
+		if client, err = google.DefaultClient(ctx, gsheetScope); err != nil {
+			return nil, err
+		}
+	}
+
+	u := fmt.Sprintf("https://sheets.googleapis.com/v4/spreadsheets/%s/values/%s", url.PathEscape(spreadsheetID), url.PathEscape(cellRange))
+	resp, err := client.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(fmt.Sprintf("Bad response fetching spreadsheet %s: %s", spreadsheetID, resp.Status))
+	}
+
+	var values struct {
+		Values [][]string `json:"values"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&values); err != nil {
+		return nil, err
+	}
+
+	return values.Values, nil
+}
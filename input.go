@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// recordReader reads rows of fields, such as csv.Reader.
+type recordReader interface {
+	Read() (record []string, err error)
+}
+
+// openDomains opens the domains file, or fetches the domains from --source.
+func openDomains() (io.ReadCloser, error) {
+	if *argsSource == "" {
+		return os.Open(*argsFile)
+	}
+
+	if !strings.HasPrefix(*argsSource, "gsheet:") {
+		return nil, errors.New(fmt.Sprintf("Unsupported source %s, expected gsheet:<spreadsheet-id>:<range>", *argsSource))
+	}
+
+	spec := strings.SplitN(strings.TrimPrefix(*argsSource, "gsheet:"), ":", 2)
+	if len(spec) != 2 || spec[0] == "" || spec[1] == "" {
+		return nil, errors.New(fmt.Sprintf("Invalid source %s, expected gsheet:<spreadsheet-id>:<range>", *argsSource))
+	}
+
+	rows, err := fetchGSheet(spec[0], spec[1])
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert the rows to CSV, so they're read like any other domains file
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.WriteAll(rows); err != nil {
+		return nil, err
+	}
+
+	return ioutil.NopCloser(&buf), nil
+}
+
+// readDomains reads the domains file as CSV, or an Excel .xlsx workbook,
+// calling fn with each domain from the column selected by --column. A file
+// with one domain per line is a CSV file with a single column.
+func readDomains(r io.Reader, fn func(domain string)) error {
+	br := bufio.NewReader(r)
+
+	var cr recordReader
+	if magic, _ := br.Peek(4); bytes.Equal(magic, []byte("PK\x03\x04")) {
+		// xlsx files are zip files, but csv files can't start with this
+		xr, err := newXLSXReader(br, *argsSheet)
+		if err != nil {
+			return err
+		}
+		cr = xr
+	} else {
+		csvr := csv.NewReader(br)
+		csvr.FieldsPerRecord = -1
+		csvr.LazyQuotes = true
+		csvr.TrimLeadingSpace = true
+		cr = csvr
+	}
+
+	col := 0
+	header := *argsHeader
+	if *argsCol != "" {
+		if n, err := strconv.Atoi(*argsCol); err == nil {
+			if n < 1 {
+				return errors.New(fmt.Sprintf("Invalid column %d, columns are numbered from 1", n))
+			}
+			col = n - 1
+		} else {
+			// Column is a name, so find it in the header
+			record, err := cr.Read()
+			if err != nil {
+				return err
+			}
+			col = -1
+			for i, name := range record {
+				if strings.EqualFold(strings.TrimSpace(name), *argsCol) {
+					col = i
+				}
+			}
+			if col == -1 {
+				return errors.New(fmt.Sprintf("Column %s not found in header", *argsCol))
+			}
+			header = false
+		}
+	}
+
+	if header {
+		if _, err := cr.Read(); err != nil {
+			return err
+		}
+	}
+
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if col >= len(record) {
+			continue
+		}
+		if domain := strings.TrimSpace(record[col]); domain != "" {
+			fn(domain)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadDomains(t *testing.T) {
+	tests := []struct {
+		input   string
+		col     string
+		header  bool
+		want    []string
+		wantErr bool
+	}{
+		{input: "example.com\n\nexample.net\n", want: []string{"example.com", "example.net"}},
+		{input: "example.com, owner\n example.net ,owner\n", want: []string{"example.com", "example.net"}},
+		{input: "domain\nexample.com\n", header: true, want: []string{"example.com"}},
+		{input: "owner,domain\nops,example.com\nops\n", col: "2", header: true, want: []string{"example.com"}},
+		{input: "Owner, Domain\nops,example.com\n", col: "domain", want: []string{"example.com"}},
+		{input: "owner,domain\n", col: "zone", wantErr: true},
+		{input: "example.com\n", col: "0", wantErr: true},
+	}
+
+	defer func(col string, header bool) {
+		*argsCol, *argsHeader = col, header
+	}(*argsCol, *argsHeader)
+
+	for _, tt := range tests {
+		*argsCol, *argsHeader = tt.col, tt.header
+
+		var got []string
+		err := readDomains(strings.NewReader(tt.input), func(domain string) {
+			got = append(got, domain)
+		})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("readDomains(%q) --column %q error %v, want error %t", tt.input, tt.col, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("readDomains(%q) --column %q got %q, want %q", tt.input, tt.col, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deckarep/golang-set"
+)
+
+// Finished jobs are kept for this long for their results to be fetched
+const jobRetention = 24 * time.Hour
+
+const (
+	jobQueued  = "queued"
+	jobRunning = "running"
+	jobDone    = "done"
+)
+
+type jobRequest struct {
+	Domains []string `json:"domains"`
+	// NameServers overrides the server's --nameserver for this job
+	NameServers []string `json:"nameservers,omitempty"`
+}
+
+type job struct {
+	ID       string     `json:"id"`
+	Status   string     `json:"status"`
+	Domains  int        `json:"domains"`
+	Checked  int        `json:"checked"`
+	Created  time.Time  `json:"created"`
+	Finished *time.Time `json:"finished,omitempty"`
+
+	domains    []string
+	requiredNS mapset.Set
+	results    []jsonDomain
+}
+
+// jobServer runs batch audit jobs one at a time, so a large batch doesn't
+// need a connection held open for its whole run.
+type jobServer struct {
+	requiredNS   mapset.Set
+	allowedAddrs mapset.Set
+
+	mu    sync.Mutex
+	jobs  map[string]*job
+	queue chan *job
+}
+
+func newJobServer(requiredNS, allowedAddrs mapset.Set) *jobServer {
+	s := &jobServer{
+		requiredNS:   requiredNS,
+		allowedAddrs: allowedAddrs,
+		jobs:         make(map[string]*job),
+		queue:        make(chan *job, 1024),
+	}
+	go s.run()
+	return s
+}
+
+func (s *jobServer) run() {
+	for j := range s.queue {
+		s.mu.Lock()
+		j.Status = jobRunning
+		s.mu.Unlock()
+
+		log.Printf("Running job %s of %d domains", j.ID, len(j.domains))
+		resetHostCaches()
+
+		inChan := make(chan string, len(j.domains))
+		for _, domain := range j.domains {
+			inChan <- domain
+		}
+		close(inChan)
+
+		results := make(chan jsonDomain)
+		var wg sync.WaitGroup
+		for i := 0; i < *argsW; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for domain := range inChan {
+					domainNS, _ := checkDomain(domain, flagOptions())
+					compareNS(j.requiredNS, s.allowedAddrs, &domainNS)
+					results <- newJSONDomain(&domainNS)
+				}
+			}()
+		}
+		go func() {
+			wg.Wait()
+			close(results)
+		}()
+
+		for result := range results {
+			s.mu.Lock()
+			j.results = append(j.results, result)
+			j.Checked++
+			s.mu.Unlock()
+		}
+
+		s.mu.Lock()
+		finished := time.Now()
+		j.Status = jobDone
+		j.Finished = &finished
+		s.mu.Unlock()
+
+		log.Printf("Finished job %s", j.ID)
+	}
+}
+
+// ServeHTTP handles POST /jobs to submit a job, GET /jobs/{id} for its status
+// and GET /jobs/{id}/results for its results once done.
+func (s *jobServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if *argsRemoteToken != "" && r.Header.Get("Authorization") != "Bearer "+*argsRemoteToken {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 1 && r.Method == "POST":
+		s.create(w, r)
+	case len(parts) == 2 && r.Method == "GET":
+		s.status(w, parts[1])
+	case len(parts) == 3 && parts[2] == "results" && r.Method == "GET":
+		s.results(w, parts[1])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *jobServer) create(w http.ResponseWriter, r *http.Request) {
+	var req jobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(req.Domains) == 0 {
+		http.Error(w, "No domains", http.StatusBadRequest)
+		return
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	j := &job{
+		ID:         hex.EncodeToString(id),
+		Status:     jobQueued,
+		Domains:    len(req.Domains),
+		Created:    time.Now(),
+		domains:    req.Domains,
+		requiredNS: s.requiredNS,
+	}
+	if len(req.NameServers) > 0 {
+		j.requiredNS = mapset.NewSet()
+		for _, ns := range req.NameServers {
+			j.requiredNS.Add(normaliseNS(ns))
+		}
+	}
+
+	s.mu.Lock()
+	for id, old := range s.jobs {
+		if old.Status == jobDone && time.Since(*old.Finished) > jobRetention {
+			delete(s.jobs, id)
+		}
+	}
+	s.jobs[j.ID] = j
+	// run may start the job as soon as it's queued, so respond with a copy
+	accepted := *j
+	s.mu.Unlock()
+
+	select {
+	case s.queue <- j:
+	default:
+		s.mu.Lock()
+		delete(s.jobs, j.ID)
+		s.mu.Unlock()
+		http.Error(w, "Too many queued jobs", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Location", "/jobs/"+j.ID)
+	s.writeJSON(w, http.StatusAccepted, &accepted)
+}
+
+func (s *jobServer) status(w http.ResponseWriter, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, ok := s.jobs[id]
+	if !ok {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, j)
+}
+
+func (s *jobServer) results(w http.ResponseWriter, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, ok := s.jobs[id]
+	if !ok {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	if j.Status != jobDone {
+		http.Error(w, "Job not finished", http.StatusConflict)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, struct {
+		SchemaVersion int          `json:"schema_version"`
+		Results       []jsonDomain `json:"results"`
+	}{schemaVersion, j.results})
+}
+
+func (s *jobServer) writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("Error writing response:", err)
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	_ "embed"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/deckarep/golang-set"
+)
+
+// schemaVersion is incremented whenever a field is removed from the JSON
+// output or changes meaning, update schema.json to match.
+const schemaVersion = 2
+
+// schema is the JSON Schema of jsonReport, printed by nsaudit schema.
+//
+//go:embed schema.json
+var schema []byte
+
+// jsonReport is the JSON representation of a run.
+type jsonReport struct {
+	SchemaVersion     int                        `json:"schema_version"`
+	Time              time.Time                  `json:"time"`
+	Domains           int                        `json:"domains"`
+	DomainsWithErrors int                        `json:"domains_with_errors"`
+	TotalErrors       int                        `json:"total_errors"`
+	ErrorClasses      map[string]int             `json:"error_classes,omitempty"`
+	Registrars        map[string]*registrarStats `json:"registrars,omitempty"`
+	Results           []jsonDomain               `json:"results"`
+}
+
+// upgradeReport converts a report read with an older schema version to the
+// current one. Before version 2 the NS names were as returned or given, they're
+// normalised as they are now, keeping the originals as the raw names.
+func upgradeReport(report *jsonReport) {
+	if report.SchemaVersion < 2 {
+		for i := range report.Results {
+			r := &report.Results[i]
+			r.RegistrarNS, r.RegistrarNSRaw = normaliseNames(r.RegistrarNS), r.RegistrarNS
+			r.ZoneNS, r.ZoneNSRaw = normaliseNames(r.ZoneNS), r.ZoneNS
+			r.RequiredNS, r.RequiredNSRaw = normaliseNames(r.RequiredNS), r.RequiredNS
+		}
+	}
+	report.SchemaVersion = schemaVersion
+}
+
+// normaliseNames returns each NS name normalised, see normaliseNS.
+func normaliseNames(names []string) []string {
+	var normalised []string
+	for _, name := range names {
+		normalised = append(normalised, normaliseNS(name))
+	}
+	return normalised
+}
+
+// jsonDomain is the JSON representation of a DomainNS.
+type jsonDomain struct {
+	Domain         string                    `json:"domain"`
+	Vantage        string                    `json:"vantage,omitempty"`
+	Error          *jsonError                `json:"error,omitempty"`
+	RegistrarNS    []string                  `json:"registrar_ns,omitempty"`
+	ZoneNS         []string                  `json:"zone_ns,omitempty"`
+	RequiredNS     []string                  `json:"required_ns,omitempty"`
+	RegistrarNSRaw []string                  `json:"registrar_ns_raw,omitempty"`
+	ZoneNSRaw      []string                  `json:"zone_ns_raw,omitempty"`
+	RequiredNSRaw  []string                  `json:"required_ns_raw,omitempty"`
+	NSHosts        map[string]jsonNSHost     `json:"ns_hosts,omitempty"`
+	DNSBL          []dnsblListing            `json:"dnsbl,omitempty"`
+	Reachability   map[string][]reachability `json:"reachability,omitempty"`
+	Fingerprints   map[string]fingerprint    `json:"fingerprints,omitempty"`
+	CaseEchoes     map[string]caseEcho       `json:"case_echoes,omitempty"`
+	ANY            map[string]anyProbe       `json:"any,omitempty"`
+	Propagation    []resolverAnswer          `json:"propagation,omitempty"`
+	Answers        []resolverAnswer          `json:"answers,omitempty"`
+	Referral       *referral                 `json:"referral,omitempty"`
+	Wildcard       *wildcard                 `json:"wildcard,omitempty"`
+	SOA            *soaTimers                `json:"soa,omitempty"`
+	ResponseSizes  []responseSize            `json:"response_sizes,omitempty"`
+	Transfers      []zoneTransfer            `json:"transfers,omitempty"`
+	ApexAddrs      []net.IP                  `json:"apex_addrs,omitempty"`
+	RDAP           *rdapDomain               `json:"rdap,omitempty"`
+	RDAPError      string                    `json:"rdap_error,omitempty"`
+	CheckErrors    map[string]string         `json:"check_errors,omitempty"`
+	Messages       []jsonMsg                 `json:"messages,omitempty"`
+	Score          int                       `json:"score"`
+}
+
+type jsonError struct {
+	Class   string `json:"class"`
+	Message string `json:"message"`
+}
+
+type jsonNSHost struct {
+	CNAME string   `json:"cname,omitempty"`
+	Addrs []net.IP `json:"addrs,omitempty"`
+	Error string   `json:"error,omitempty"`
+}
+
+type jsonMsg struct {
+	Severity   string `json:"severity"`
+	Check      string `json:"check,omitempty"`
+	Message    string `json:"message"`
+	Suppressed string `json:"suppressed,omitempty"`
+}
+
+var priNames = []string{
+	LOG_DIFF:    "DIFF",
+	LOG_INFO:    "INFO",
+	LOG_WARNING: "WARN",
+	LOG_ERR:     "ERR",
+	LOG_CRIT:    "CRIT",
+}
+
+func newJSONDomain(domainNS *DomainNS) jsonDomain {
+	j := jsonDomain{
+		Domain:         domainNS.Domain,
+		Vantage:        domainNS.Vantage,
+		DNSBL:          domainNS.DNSBL,
+		Reachability:   domainNS.Reachability,
+		Fingerprints:   domainNS.Fingerprints,
+		CaseEchoes:     domainNS.CaseEchoes,
+		ANY:            domainNS.ANY,
+		Propagation:    domainNS.Propagation,
+		Answers:        domainNS.Answers,
+		Referral:       domainNS.Referral,
+		Wildcard:       domainNS.Wildcard,
+		SOA:            domainNS.SOA,
+		ResponseSizes:  domainNS.ResponseSizes,
+		Transfers:      domainNS.Transfers,
+		ApexAddrs:      domainNS.ApexAddrs,
+		RDAP:           domainNS.RDAP,
+		RegistrarNSRaw: domainNS.RegistrarNSRaw,
+		ZoneNSRaw:      domainNS.ZoneNSRaw,
+		RequiredNSRaw:  domainNS.RequiredNSRaw,
+	}
+
+	if domainNS.Error != nil {
+		j.Error = &jsonError{Class: domainNS.Error.Class.String(), Message: domainNS.Error.Error()}
+	}
+	if domainNS.RegistrarNS != nil {
+		j.RegistrarNS = sortedSet(domainNS.RegistrarNS)
+	}
+	if domainNS.ZoneNS != nil {
+		j.ZoneNS = sortedSet(domainNS.ZoneNS)
+	}
+	if domainNS.RequiredNS != nil {
+		j.RequiredNS = sortedSet(domainNS.RequiredNS)
+	}
+	if domainNS.NSHosts != nil {
+		j.NSHosts = make(map[string]jsonNSHost)
+		for host, h := range domainNS.NSHosts {
+			jh := jsonNSHost{CNAME: h.CNAME, Addrs: h.Addrs}
+			if h.Error != nil {
+				jh.Error = h.Error.Error()
+			}
+			j.NSHosts[host] = jh
+		}
+	}
+	if domainNS.RDAPError != nil {
+		j.RDAPError = domainNS.RDAPError.Error()
+	}
+	if domainNS.CheckErrors != nil {
+		j.CheckErrors = make(map[string]string)
+		for check, err := range domainNS.CheckErrors {
+			j.CheckErrors[check] = err.Error()
+		}
+	}
+	j.Score = domainNS.Score
+	for _, msg := range domainNS.MSGs {
+		j.Messages = append(j.Messages, jsonMsg{Severity: priNames[msg.pri], Check: msg.check, Message: msg.msg, Suppressed: msg.suppressed})
+	}
+
+	return j
+}
+
+// DomainNS converts back to a DomainNS, as if checkDomain had been called
+// locally.
+func (j jsonDomain) DomainNS() DomainNS {
+	domainNS := DomainNS{
+		Domain:         j.Domain,
+		Vantage:        j.Vantage,
+		DNSBL:          j.DNSBL,
+		Reachability:   j.Reachability,
+		Fingerprints:   j.Fingerprints,
+		CaseEchoes:     j.CaseEchoes,
+		ANY:            j.ANY,
+		Propagation:    j.Propagation,
+		Answers:        j.Answers,
+		Referral:       j.Referral,
+		Wildcard:       j.Wildcard,
+		SOA:            j.SOA,
+		ResponseSizes:  j.ResponseSizes,
+		Transfers:      j.Transfers,
+		ApexAddrs:      j.ApexAddrs,
+		RDAP:           j.RDAP,
+		RegistrarNSRaw: j.RegistrarNSRaw,
+		ZoneNSRaw:      j.ZoneNSRaw,
+		RequiredNSRaw:  j.RequiredNSRaw,
+	}
+
+	if j.Error != nil {
+		class := ErrUnknown
+		for c, name := range errorClassNames {
+			if name == j.Error.Class {
+				class = ErrorClass(c)
+			}
+		}
+		domainNS.Error = &DomainError{Class: class, Err: errors.New(j.Error.Message)}
+	} else {
+		// Empty sets are omitted, but compareNS expects them to be set
+		domainNS.RegistrarNS = setFromStrings(j.RegistrarNS)
+		domainNS.ZoneNS = setFromStrings(j.ZoneNS)
+	}
+	if j.RequiredNS != nil {
+		domainNS.RequiredNS = setFromStrings(j.RequiredNS)
+	}
+	if j.NSHosts != nil {
+		domainNS.NSHosts = make(map[string]nsHost)
+		for host, jh := range j.NSHosts {
+			h := nsHost{CNAME: jh.CNAME, Addrs: jh.Addrs}
+			if jh.Error != "" {
+				h.Error = errors.New(jh.Error)
+			}
+			domainNS.NSHosts[host] = h
+		}
+	}
+	if j.RDAPError != "" {
+		domainNS.RDAPError = errors.New(j.RDAPError)
+	}
+	if j.CheckErrors != nil {
+		domainNS.CheckErrors = make(map[string]error)
+		for check, msg := range j.CheckErrors {
+			domainNS.CheckErrors[check] = errors.New(msg)
+		}
+	}
+	for _, jm := range j.Messages {
+		for pri, name := range priNames {
+			if name == jm.Severity {
+				domainNS.MSGs = append(domainNS.MSGs, msg{pri: pri, check: jm.Check, msg: jm.Message, suppressed: jm.Suppressed})
+			}
+		}
+	}
+	domainNS.Score = j.Score
+
+	return domainNS
+}
+
+func setFromStrings(s []string) mapset.Set {
+	set := mapset.NewSet()
+	for _, v := range s {
+		set.Add(v)
+	}
+	return set
+}
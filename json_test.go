@@ -0,0 +1,43 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUpgradeReport(t *testing.T) {
+	report := jsonReport{
+		SchemaVersion: 1,
+		Results: []jsonDomain{{
+			Domain:      "example.com.",
+			RegistrarNS: []string{"NS1.Example.NET.", "ns2.example.net."},
+			ZoneNS:      []string{"ns1.example.net."},
+			RequiredNS:  []string{"ns1.example.net"},
+		}},
+	}
+	upgradeReport(&report)
+
+	if report.SchemaVersion != schemaVersion {
+		t.Errorf("schema version got %d, want %d", report.SchemaVersion, schemaVersion)
+	}
+	r := report.Results[0]
+	if want := []string{"ns1.example.net.", "ns2.example.net."}; !reflect.DeepEqual(r.RegistrarNS, want) {
+		t.Errorf("registrar NS got %v, want %v", r.RegistrarNS, want)
+	}
+	if want := []string{"NS1.Example.NET.", "ns2.example.net."}; !reflect.DeepEqual(r.RegistrarNSRaw, want) {
+		t.Errorf("registrar NS raw got %v, want %v", r.RegistrarNSRaw, want)
+	}
+	if want := []string{"ns1.example.net."}; !reflect.DeepEqual(r.RequiredNS, want) {
+		t.Errorf("required NS got %v, want %v", r.RequiredNS, want)
+	}
+
+	// Current reports are already normalised
+	current := jsonReport{
+		SchemaVersion: schemaVersion,
+		Results:       []jsonDomain{{Domain: "example.com.", ZoneNS: []string{"ns1.example.net."}, ZoneNSRaw: []string{"NS1.example.net."}}},
+	}
+	upgradeReport(&current)
+	if want := []string{"NS1.example.net."}; !reflect.DeepEqual(current.Results[0].ZoneNSRaw, want) {
+		t.Errorf("current report's zone NS raw got %v, want %v", current.Results[0].ZoneNSRaw, want)
+	}
+}
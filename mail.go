@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+// Time allowed for the whole SMTP session, after connecting
+const smtpTimeout = 2 * time.Minute
+
+// mailAttachment is a file attached to the report email.
+type mailAttachment struct {
+	name        string
+	contentType string
+	body        *bytes.Buffer
+}
+
+// mailReport collects the outputs emailed after each run, see --mail-to.
+type mailReport struct {
+	to          []string
+	summary     bytes.Buffer
+	attachments []mailAttachment
+}
+
+// newMailReport returns the report to the addresses and the writers producing
+// its summary and attachments, which are added to the run's outputs.
+func newMailReport(to []string) (*mailReport, []outputWriter) {
+	m := &mailReport{
+		to: to,
+		attachments: []mailAttachment{
+			{name: "nsaudit.csv", contentType: "text/csv", body: new(bytes.Buffer)},
+			{name: "nsaudit.html", contentType: "text/html", body: new(bytes.Buffer)},
+		},
+	}
+	return m, []outputWriter{
+		mailSummaryWriter{&textWriter{w: nopCloser{&m.summary}}},
+		newCSVWriter(nopCloser{m.attachments[0].body}),
+		&htmlWriter{jsonWriter{w: nopCloser{m.attachments[1].body}}},
+	}
+}
+
+// mailSummaryWriter only writes the text output's stats, the domains are in
+// the attachments.
+type mailSummaryWriter struct {
+	*textWriter
+}
+
+func (mailSummaryWriter) Domain(domainNS *DomainNS) error { return nil }
+
+// send emails the report to each of its addresses via --smtp. Implicit TLS is
+// used with --smtp-tls, otherwise STARTTLS if the server supports it.
+func (m *mailReport) send(stats *auditStats) error {
+
+	host, _, err := net.SplitHostPort(*argsSMTP)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(*argsTO)*time.Second)
+	defer cancel()
+	conn, err := dialTCP(ctx, "tcp", *argsSMTP)
+	if err != nil {
+		return err
+	}
+	// A stalled server mustn't hang the run, or every scheduled run after it
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	tlsConfig := &tls.Config{ServerName: host}
+	if *argsSMTPTLS {
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if !*argsSMTPTLS {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err := c.StartTLS(tlsConfig); err != nil {
+				return err
+			}
+		}
+	}
+
+	if *argsSMTPUser != "" {
+		if err := c.Auth(smtp.PlainAuth("", *argsSMTPUser, os.Getenv("NSAUDIT_SMTP_PASSWORD"), host)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(*argsMailFrom); err != nil {
+		return err
+	}
+	for _, to := range m.to {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(m.message(stats)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+// message returns the MIME message with the summary as the body.
+func (m *mailReport) message(stats *auditStats) []byte {
+	b := make([]byte, 16)
+	rand.Read(b)
+	boundary := hex.EncodeToString(b)
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", *argsMailFrom)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(m.to, ", "))
+	fmt.Fprintf(&msg, "Subject: nsaudit: %d of %d domains with errors\r\n", stats.DomainsWithErrors, stats.Domains)
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", boundary)
+
+	fmt.Fprintf(&msg, "--%s\r\n", boundary)
+	fmt.Fprintf(&msg, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	fmt.Fprintf(&msg, "nsaudit run started %s\r\n", stats.Started.Format("2006-01-02 15:04:05 MST"))
+	msg.WriteString(strings.Replace(m.summary.String(), "\n", "\r\n", -1))
+
+	for _, a := range m.attachments {
+		fmt.Fprintf(&msg, "\r\n--%s\r\n", boundary)
+		fmt.Fprintf(&msg, "Content-Type: %s; charset=utf-8\r\n", a.contentType)
+		fmt.Fprintf(&msg, "Content-Disposition: attachment; filename=%q\r\n", a.name)
+		fmt.Fprintf(&msg, "Content-Transfer-Encoding: base64\r\n\r\n")
+
+		// Base64 lines are limited to 76 characters, see RFC 2045
+		encoded := base64.StdEncoding.EncodeToString(a.body.Bytes())
+		for len(encoded) > 76 {
+			msg.WriteString(encoded[:76] + "\r\n")
+			encoded = encoded[76:]
+		}
+		msg.WriteString(encoded + "\r\n")
+	}
+	fmt.Fprintf(&msg, "--%s--\r\n", boundary)
+
+	return msg.Bytes()
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// mockAddr is the address of the mock server, when set every query is sent
+// to it regardless of the name server it was meant for.
+var mockAddr string
+
+// mockServer answers queries from zone files, standing in for every name
+// server so delegations can be tested offline.
+type mockServer struct {
+	// zones maps each zone's origin to its records
+	zones map[string][]dns.RR
+}
+
+// loadMockZones reads each *.zone file in dir, the file name without the
+// extension is the zone's origin, and root.zone is the root zone.
+func loadMockZones(dir string) (*mockServer, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.zone"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, errors.New(fmt.Sprintf("No zone files found in %s", dir))
+	}
+
+	s := &mockServer{zones: make(map[string][]dns.RR)}
+	for _, file := range files {
+		origin := strings.TrimSuffix(filepath.Base(file), ".zone")
+		if origin == "root" {
+			origin = "."
+		}
+		origin = dns.Fqdn(strings.ToLower(origin))
+
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+
+		zp := dns.NewZoneParser(f, origin, file)
+		for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+			s.zones[origin] = append(s.zones[origin], rr)
+		}
+		f.Close()
+		if err := zp.Err(); err != nil {
+			return nil, err
+		}
+		log.Printf("Loaded mock zone %s with %d records", origin, len(s.zones[origin]))
+	}
+
+	return s, nil
+}
+
+// closest returns the longest loaded zone that name is in, excluding name
+// itself if below is set.
+func (s *mockServer) closest(name string, below bool) (zone string, ok bool) {
+	for origin := range s.zones {
+		if below && origin == name {
+			continue
+		}
+		if dns.IsSubDomain(origin, name) && dns.CountLabel(origin) >= dns.CountLabel(zone) {
+			zone, ok = origin, true
+		}
+	}
+	return
+}
+
+// ServeDNS answers from the closest zone. As the same server stands in for
+// both the parent and child name servers, a query for a zone cut includes the
+// child's records in the answer and the parent's delegation in the authority
+// section.
+func (s *mockServer) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Authoritative = true
+
+	if len(r.Question) != 1 {
+		m.Rcode = dns.RcodeFormatError
+		w.WriteMsg(m)
+		return
+	}
+	q := r.Question[0]
+	name := strings.ToLower(q.Name)
+
+	zone, ok := s.closest(name, false)
+	if !ok {
+		m.Rcode = dns.RcodeRefused
+		w.WriteMsg(m)
+		return
+	}
+
+	exists := name == zone
+	for _, rr := range s.zones[zone] {
+		if !strings.EqualFold(rr.Header().Name, name) {
+			continue
+		}
+		exists = true
+		switch {
+		case rr.Header().Rrtype == dns.TypeNS && name != zone:
+			// A delegation to a zone that isn't loaded, refer to it
+			m.Ns = append(m.Ns, rr)
+			m.Authoritative = false
+		case rr.Header().Rrtype == q.Qtype || rr.Header().Rrtype == dns.TypeCNAME:
+			m.Answer = append(m.Answer, rr)
+		}
+	}
+	if !exists {
+		m.Rcode = dns.RcodeNameError
+	}
+	if len(m.Ns) > 0 {
+		m.Answer = nil
+	}
+
+	if parent, ok := s.closest(name, true); ok && name == zone {
+		for _, rr := range s.zones[parent] {
+			if rr.Header().Rrtype == dns.TypeNS && strings.EqualFold(rr.Header().Name, name) {
+				m.Ns = append(m.Ns, rr)
+			}
+		}
+	}
+
+	w.WriteMsg(m)
+}
+
+// startMock serves the zone files in dir on a local port, over UDP and TCP,
+// and sends all future queries, including those via the system's resolvers,
+// to it.
+func startMock(dir string) error {
+	s, err := loadMockZones(dir)
+	if err != nil {
+		return err
+	}
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	l, err := net.Listen("tcp", pc.LocalAddr().String())
+	if err != nil {
+		pc.Close()
+		return err
+	}
+
+	go func() {
+		log.Fatal((&dns.Server{PacketConn: pc, Handler: s}).ActivateAndServe())
+	}()
+	go func() {
+		log.Fatal((&dns.Server{Listener: l, Handler: s}).ActivateAndServe())
+	}()
+
+	mockAddr = pc.LocalAddr().String()
+	resolver = &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			return dialer(network).DialContext(ctx, network, mockAddr)
+		},
+	}
+
+	log.Println("Serving mock zones on:", mockAddr)
+	return nil
+}
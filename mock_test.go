@@ -0,0 +1,103 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/deckarep/golang-set"
+)
+
+var mockOnce sync.Once
+
+// startTestMock serves testdata/zones for every query, once for all tests.
+func startTestMock(t *testing.T) {
+	t.Helper()
+
+	var err error
+	mockOnce.Do(func() {
+		serverBreaker = newBreaker(0, 0)
+		err = startMock("testdata/zones")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestMockAudit checks the domains in testdata/domains.txt against the zones
+// in testdata/zones, as nsaudit -f testdata/domains.txt --mock-zone would.
+func TestMockAudit(t *testing.T) {
+	startTestMock(t)
+
+	requiredNS := mapset.NewSet("ns1.example.net.", "ns2.example.net.")
+
+	tests := []struct {
+		domain      string
+		registrarNS []string
+		zoneNS      []string
+		// checks raising messages, sorted
+		checks []string
+	}{
+		{
+			domain:      "example.com",
+			registrarNS: []string{"ns1.example.net.", "ns2.example.net."},
+			zoneNS:      []string{"ns1.example.net.", "ns2.example.net."},
+		},
+		{
+			domain:      "mismatch.com",
+			registrarNS: []string{"ns1.example.net.", "ns2.example.net."},
+			zoneNS:      []string{"ns1.example.net.", "ns9.example.net."},
+			checks:      []string{checkZone},
+		},
+		{
+			// Delegated to servers without a zone file, so they're lame
+			domain: "lame.com",
+			checks: []string{checkError},
+		},
+	}
+
+	for _, tt := range tests {
+		domainNS, _ := checkDomain(tt.domain, checkOptions{})
+		compareNS(requiredNS, mapset.NewSet(), &domainNS)
+
+		if tt.registrarNS != nil {
+			if got := sortedSet(domainNS.RegistrarNS); !reflect.DeepEqual(got, tt.registrarNS) {
+				t.Errorf("%s registrar NS got %v, want %v", tt.domain, got, tt.registrarNS)
+			}
+			if got := sortedSet(domainNS.ZoneNS); !reflect.DeepEqual(got, tt.zoneNS) {
+				t.Errorf("%s zone NS got %v, want %v", tt.domain, got, tt.zoneNS)
+			}
+		}
+
+		var checks []string
+		for _, msg := range domainNS.MSGs {
+			checks = append(checks, msg.check)
+		}
+		sort.Strings(checks)
+		if !reflect.DeepEqual(checks, tt.checks) {
+			t.Errorf("%s messages from checks %v, want %v: %v", tt.domain, checks, tt.checks, domainNS.MSGs)
+		}
+	}
+}
+
+// TestMockOptionalChecks checks optional checks' data is collected from the
+// fixture zones.
+func TestMockOptionalChecks(t *testing.T) {
+	startTestMock(t)
+
+	domainNS, err := checkDomain("example.com", checkOptions{SOA: true, Apex: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if domainNS.SOA == nil || domainNS.SOA.Refresh != 7200 || domainNS.SOA.Expire != 1209600 {
+		t.Errorf("SOA got %+v, want refresh 7200 and expire 1209600", domainNS.SOA)
+	}
+	if len(domainNS.ApexAddrs) != 1 || domainNS.ApexAddrs[0].String() != "192.0.2.1" {
+		t.Errorf("apex addresses got %v, want [192.0.2.1]", domainNS.ApexAddrs)
+	}
+	if len(domainNS.CheckErrors) > 0 {
+		t.Errorf("optional checks failed: %v", domainNS.CheckErrors)
+	}
+}
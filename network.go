@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/proxy"
+)
+
+var (
+	// sourceIP is the local address queries are sent from, nil lets the
+	// kernel choose.
+	sourceIP net.IP
+
+	// proxyDialer is the SOCKS5 proxy all TCP connections are made through,
+	// nil connects directly.
+	proxyDialer proxy.ContextDialer
+
+	// resolver is used for lookups via the system's recursive resolvers.
+	resolver = net.DefaultResolver
+
+	// noSystemResolver is set when resolver uses the fallbackResolvers
+	// instead of the system's.
+	noSystemResolver bool
+)
+
+// parseSourceIP accepts either an address or an interface name, using the
+// interface's first address.
+func parseSourceIP(s string) (net.IP, error) {
+	if ip := net.ParseIP(s); ip != nil {
+		return ip, nil
+	}
+
+	iface, err := net.InterfaceByName(s)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, addr := range addrs {
+		if ipnet, ok := addr.(*net.IPNet); ok {
+			return ipnet.IP, nil
+		}
+	}
+
+	return nil, errors.New(fmt.Sprintf("No addresses on interface %s", s))
+}
+
+// setSourceIP binds all future queries, including those via the system's
+// resolvers, to the local address.
+func setSourceIP(ip net.IP) {
+	sourceIP = ip
+	resolver = &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			return dialer(network).DialContext(ctx, network, address)
+		},
+	}
+}
+
+// setProxy routes all future queries through a SOCKS5 proxy. SOCKS5 UDP isn't
+// supported, so queries, including those via the system's resolvers, switch
+// to TCP.
+func setProxy(rawurl string) error {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return err
+	}
+
+	if u.Scheme != "socks5" && u.Scheme != "socks5h" {
+		return errors.New(fmt.Sprintf("Unsupported proxy scheme %s, only socks5 is supported", u.Scheme))
+	}
+
+	d, err := proxy.FromURL(u, dialer("tcp"))
+	if err != nil {
+		return err
+	}
+
+	var ok bool
+	if proxyDialer, ok = d.(proxy.ContextDialer); !ok {
+		return errors.New("Proxy does not support dialing with a context")
+	}
+
+	resolver = &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			// The resolver uses TCP when Dial doesn't return a net.PacketConn
+			return proxyDialer.DialContext(ctx, "tcp", address)
+		},
+	}
+
+	return nil
+}
+
+// dialTCP connects to address via the proxy, if set.
+func dialTCP(ctx context.Context, network, address string) (net.Conn, error) {
+	if proxyDialer != nil {
+		return proxyDialer.DialContext(ctx, network, address)
+	}
+	return dialer(network).DialContext(ctx, network, address)
+}
+
+// exchange sends a single query to address, over TCP via the proxy if set,
+// otherwise UDP.
+func exchange(m *dns.Msg, address string) (r *dns.Msg, err error) {
+	return exchangeContext(context.Background(), m, address)
+}
+
+// exchangeContext is exchange, giving up when ctx is done.
+func exchangeContext(ctx context.Context, m *dns.Msg, address string) (r *dns.Msg, err error) {
+	network := "udp"
+	if proxyDialer != nil {
+		network = "tcp"
+	}
+	r, _, err = exchangeNetContext(ctx, m, address, network)
+	return
+}
+
+// exchangeNet sends a single query to address over network, either udp or tcp,
+// returning the response and round trip time. Only tcp is supported via the
+// proxy. Queries go to the mock server instead, if running.
+func exchangeNet(m *dns.Msg, address, network string) (r *dns.Msg, rtt time.Duration, err error) {
+	return exchangeNetContext(context.Background(), m, address, network)
+}
+
+// exchangeNetContext is exchangeNet, giving up when ctx is done. The query
+// still times out after --timeout, or the domain's overridden timeout, if ctx
+// has longer left. Queries to servers with a --tsig-server key are signed.
+func exchangeNetContext(ctx context.Context, m *dns.Msg, address, network string) (r *dns.Msg, rtt time.Duration, err error) {
+	var secrets map[string]string
+	if key := serverKey(address); key != nil {
+		m = signMsg(m, key)
+		secrets = key.secrets()
+	}
+
+	if mockAddr != "" {
+		address = mockAddr
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, settingsFrom(ctx).timeout)
+	defer cancel()
+
+	if proxyDialer == nil {
+		c := dns.Client{Net: network, Dialer: dialer(network), TsigSecret: secrets}
+		return c.ExchangeContext(ctx, m, address)
+	}
+
+	if network != "tcp" {
+		return nil, 0, errors.New(fmt.Sprintf("Can't query over %s via a SOCKS5 proxy", network))
+	}
+
+	start := time.Now()
+	conn, err := proxyDialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c := dns.Client{Net: "tcp", TsigSecret: secrets}
+	r, _, err = c.ExchangeWithConn(m, &dns.Conn{Conn: conn})
+	return r, time.Since(start), err
+}
+
+// dialer returns a dialer for the network bound to sourceIP, if set.
+func dialer(network string) *net.Dialer {
+	d := &net.Dialer{Timeout: time.Duration(*argsTO) * time.Second}
+	if sourceIP == nil {
+		return d
+	}
+
+	switch network {
+	case "udp", "udp4", "udp6":
+		d.LocalAddr = &net.UDPAddr{IP: sourceIP}
+	default:
+		d.LocalAddr = &net.TCPAddr{IP: sourceIP}
+	}
+	return d
+}
@@ -0,0 +1,206 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.25.3
+// source: nsauditpb/nsaudit.proto
+
+package nsauditpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	NSAudit_Audit_FullMethodName = "/nsaudit.v1.NSAudit/Audit"
+	NSAudit_Watch_FullMethodName = "/nsaudit.v1.NSAudit/Watch"
+)
+
+// NSAuditClient is the client API for NSAudit service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type NSAuditClient interface {
+	// Audit checks each domain, streaming each result as soon as it's checked.
+	Audit(ctx context.Context, in *AuditRequest, opts ...grpc.CallOption) (NSAudit_AuditClient, error)
+	// Watch checks the domains every interval until cancelled, streaming each
+	// domain's NS records the first time they're seen and whenever they change.
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (NSAudit_WatchClient, error)
+}
+
+type nSAuditClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewNSAuditClient(cc grpc.ClientConnInterface) NSAuditClient {
+	return &nSAuditClient{cc}
+}
+
+func (c *nSAuditClient) Audit(ctx context.Context, in *AuditRequest, opts ...grpc.CallOption) (NSAudit_AuditClient, error) {
+	stream, err := c.cc.NewStream(ctx, &NSAudit_ServiceDesc.Streams[0], NSAudit_Audit_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &nSAuditAuditClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type NSAudit_AuditClient interface {
+	Recv() (*DomainResult, error)
+	grpc.ClientStream
+}
+
+type nSAuditAuditClient struct {
+	grpc.ClientStream
+}
+
+func (x *nSAuditAuditClient) Recv() (*DomainResult, error) {
+	m := new(DomainResult)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *nSAuditClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (NSAudit_WatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &NSAudit_ServiceDesc.Streams[1], NSAudit_Watch_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &nSAuditWatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type NSAudit_WatchClient interface {
+	Recv() (*NSChange, error)
+	grpc.ClientStream
+}
+
+type nSAuditWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *nSAuditWatchClient) Recv() (*NSChange, error) {
+	m := new(NSChange)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// NSAuditServer is the server API for NSAudit service.
+// All implementations must embed UnimplementedNSAuditServer
+// for forward compatibility
+type NSAuditServer interface {
+	// Audit checks each domain, streaming each result as soon as it's checked.
+	Audit(*AuditRequest, NSAudit_AuditServer) error
+	// Watch checks the domains every interval until cancelled, streaming each
+	// domain's NS records the first time they're seen and whenever they change.
+	Watch(*WatchRequest, NSAudit_WatchServer) error
+	mustEmbedUnimplementedNSAuditServer()
+}
+
+// UnimplementedNSAuditServer must be embedded to have forward compatible implementations.
+type UnimplementedNSAuditServer struct {
+}
+
+func (UnimplementedNSAuditServer) Audit(*AuditRequest, NSAudit_AuditServer) error {
+	return status.Errorf(codes.Unimplemented, "method Audit not implemented")
+}
+func (UnimplementedNSAuditServer) Watch(*WatchRequest, NSAudit_WatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedNSAuditServer) mustEmbedUnimplementedNSAuditServer() {}
+
+// UnsafeNSAuditServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to NSAuditServer will
+// result in compilation errors.
+type UnsafeNSAuditServer interface {
+	mustEmbedUnimplementedNSAuditServer()
+}
+
+func RegisterNSAuditServer(s grpc.ServiceRegistrar, srv NSAuditServer) {
+	s.RegisterService(&NSAudit_ServiceDesc, srv)
+}
+
+func _NSAudit_Audit_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(AuditRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(NSAuditServer).Audit(m, &nSAuditAuditServer{stream})
+}
+
+type NSAudit_AuditServer interface {
+	Send(*DomainResult) error
+	grpc.ServerStream
+}
+
+type nSAuditAuditServer struct {
+	grpc.ServerStream
+}
+
+func (x *nSAuditAuditServer) Send(m *DomainResult) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _NSAudit_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(NSAuditServer).Watch(m, &nSAuditWatchServer{stream})
+}
+
+type NSAudit_WatchServer interface {
+	Send(*NSChange) error
+	grpc.ServerStream
+}
+
+type nSAuditWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *nSAuditWatchServer) Send(m *NSChange) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// NSAudit_ServiceDesc is the grpc.ServiceDesc for NSAudit service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var NSAudit_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "nsaudit.v1.NSAudit",
+	HandlerType: (*NSAuditServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Audit",
+			Handler:       _NSAudit_Audit_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Watch",
+			Handler:       _NSAudit_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "nsauditpb/nsaudit.proto",
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// runNSHealth checks each name server directly, independent of any domains,
+// returning the priority of the most severe failure.
+func runNSHealth(hosts []string) (worst int) {
+
+	worst = LOG_DIFF
+
+	probe := dns.Fqdn(*argsProbe)
+	if *argsProbe == "" {
+		probe = "."
+	}
+
+	for _, host := range hosts {
+		host = strings.TrimRight(host, ".") + "."
+		fmt.Printf("----- %s -----\n", host)
+
+		addrs, err := resolver.LookupIPAddr(context.Background(), host)
+		if err != nil {
+			fmt.Println("CRIT: Could not resolve:", err)
+			worst = LOG_CRIT
+			continue
+		}
+
+		for _, addr := range addrs {
+			if pri := nsHealth(addr.IP, probe); pri > worst {
+				worst = pri
+			}
+		}
+
+		fmt.Println()
+	}
+
+	return
+}
+
+// nsHealth queries the name server at ip for the probe zone's SOA over UDP and
+// TCP, printing the latency, whether it's authoritative for the probe zone
+// and its version.bind.
+func nsHealth(ip net.IP, probe string) (worst int) {
+
+	worst = LOG_DIFF
+	address := net.JoinHostPort(ip.String(), "53")
+
+	m := new(dns.Msg)
+	m.SetQuestion(probe, dns.TypeSOA)
+
+	var results []string
+	authoritative := false
+	for _, network := range []string{"udp", "tcp"} {
+		r, rtt, err := exchangeNet(m, address, network)
+		if err != nil {
+			results = append(results, fmt.Sprintf("%s: fail (%s)", strings.ToUpper(network), err))
+			worst = LOG_ERR
+			continue
+		}
+		results = append(results, fmt.Sprintf("%s: ok %s", strings.ToUpper(network), rtt.Round(time.Millisecond)))
+		authoritative = authoritative || (r.Authoritative && r.Rcode == dns.RcodeSuccess)
+	}
+	fmt.Printf("%s %s\n", ip, strings.Join(results, ", "))
+
+	if *argsProbe != "" {
+		if authoritative {
+			fmt.Printf("%s AA: yes for %s\n", ip, probe)
+		} else {
+			fmt.Printf("%s AA: no for %s\n", ip, probe)
+			worst = LOG_ERR
+		}
+	}
+
+	if version, err := versionBind(context.Background(), address); err != nil {
+		fmt.Printf("%s version.bind: unknown (%s)\n", ip, err)
+	} else {
+		fmt.Printf("%s version.bind: %q\n", ip, version)
+	}
+
+	return
+}
+
+// versionBind returns the server's CHAOS version.bind TXT record, many servers
+// refuse or hide this so it's informational only.
+func versionBind(ctx context.Context, address string) (string, error) {
+	m := new(dns.Msg)
+	m.SetQuestion("version.bind.", dns.TypeTXT)
+	m.Question[0].Qclass = dns.ClassCHAOS
+
+	r, err := exchangeContext(ctx, m, address)
+	if err != nil {
+		return "", err
+	}
+	if r.Rcode != dns.RcodeSuccess {
+		return "", errors.New(dns.RcodeToString[r.Rcode])
+	}
+
+	for _, a := range r.Answer {
+		if txt, ok := a.(*dns.TXT); ok {
+			return strings.Join(txt.Txt, ""), nil
+		}
+	}
+	return "", errors.New("no TXT record")
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// nsHost is a name server's hostname resolved to its addresses.
+type nsHost struct {
+	// CNAME is the canonical name the hostname points at, empty if the
+	// hostname isn't a CNAME
+	CNAME string
+	Addrs []net.IP
+	Error error
+}
+
+// nsHostEntry is a cached nsHost, failed lookups expire so they're retried.
+type nsHostEntry struct {
+	host    nsHost
+	expires time.Time
+}
+
+var (
+	nsHostMu    sync.Mutex
+	nsHostCache = make(map[string]nsHostEntry)
+)
+
+// resetNSHostCache forgets the cached hosts, see resetHostCaches.
+func resetNSHostCache() {
+	nsHostMu.Lock()
+	nsHostCache = make(map[string]nsHostEntry)
+	nsHostMu.Unlock()
+}
+
+// resolveNSHost follows host to its canonical name and addresses, name
+// servers are shared by many domains so results are cached for the run, and
+// failed lookups for --negative-cache-ttl.
+func resolveNSHost(ctx context.Context, host string) nsHost {
+	nsHostMu.Lock()
+	cached, ok := nsHostCache[host]
+	nsHostMu.Unlock()
+	if ok && (cached.expires.IsZero() || time.Now().Before(cached.expires)) {
+		return cached.host
+	}
+
+	var h nsHost
+
+	cname, err := resolver.LookupCNAME(ctx, host)
+	if err != nil {
+		h.Error = err
+	} else if !strings.EqualFold(strings.TrimRight(cname, "."), strings.TrimRight(host, ".")) {
+		h.CNAME = cname
+	}
+
+	if h.Error == nil {
+		addrs, err := resolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			h.Error = err
+		}
+		for _, addr := range addrs {
+			h.Addrs = append(h.Addrs, addr.IP)
+		}
+	}
+
+	// Lookups cut short by the domain's deadline say nothing about the host
+	if ctx.Err() != nil {
+		return h
+	}
+
+	entry := nsHostEntry{host: h}
+	if h.Error != nil {
+		entry.expires = time.Now().Add(time.Duration(*argsNegTTL) * time.Second)
+	}
+
+	nsHostMu.Lock()
+	nsHostCache[host] = entry
+	nsHostMu.Unlock()
+
+	return h
+}
+
+// resolveNSHosts resolves all hosts in the registrar and zone NS sets.
+func resolveNSHosts(ctx context.Context, domainNS *DomainNS) map[string]nsHost {
+	hosts := make(map[string]nsHost)
+	for host := range domainNS.RegistrarNS.Union(domainNS.ZoneNS).Iter() {
+		log.Println("Resolving NS host:", host)
+		hosts[host.(string)] = resolveNSHost(ctx, host.(string))
+	}
+	return hosts
+}
+
+func compareNSHosts(domainNS *DomainNS) (errors int) {
+
+	var hosts []string
+	for host := range domainNS.NSHosts {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	for _, host := range hosts {
+		h := domainNS.NSHosts[host]
+		if h.CNAME != "" {
+			domainNS.MSGs = append(domainNS.MSGs, msg{pri: LOG_ERR, check: checkNSHosts, msg: fmt.Sprintf("NS host %s is a CNAME to %s, NS records must not point at a CNAME (RFC 2181 section 10.3)", host, h.CNAME)})
+			errors++
+		}
+		if h.Error != nil {
+			domainNS.MSGs = append(domainNS.MSGs, msg{pri: LOG_ERR, check: checkNSHosts, msg: fmt.Sprintf("Could not resolve NS host %s: %s", host, h.Error)})
+			errors++
+		}
+	}
+
+	return
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Normalisation rules turned off with --strict-ns
+const (
+	strictCase = "case"
+	strictDot  = "dot"
+)
+
+// strictNS are the normalisation rules turned off, see normaliseNS.
+var strictNS = make(map[string]bool)
+
+// parseStrictNS turns off each normalisation rule, case or dot.
+func parseStrictNS(rules []string) error {
+	for _, rule := range rules {
+		switch rule {
+		case strictCase, strictDot:
+			strictNS[rule] = true
+		default:
+			return errors.New(fmt.Sprintf("Unknown rule %s, expected %s or %s", rule, strictCase, strictDot))
+		}
+	}
+	return nil
+}
+
+// normaliseNS returns the NS name as compared between the required, registrar
+// and zone sets: lower cased, as names are case insensitive, and fully
+// qualified with a trailing dot. Strict audits turn either rule off, so
+// NS1.Example.COM. or a required ns1.example.com without its dot differ.
+func normaliseNS(ns string) string {
+	if !strictNS[strictDot] {
+		ns = strings.TrimRight(ns, ".") + "."
+	}
+	if !strictNS[strictCase] {
+		ns = strings.ToLower(ns)
+	}
+	return ns
+}
@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestNormaliseNS(t *testing.T) {
+	tests := []struct {
+		strict []string
+		ns     string
+		want   string
+	}{
+		{nil, "ns1.example.com", "ns1.example.com."},
+		{nil, "NS1.Example.COM.", "ns1.example.com."},
+		{nil, "ns1.example.com..", "ns1.example.com."},
+		{[]string{strictCase}, "NS1.Example.COM", "NS1.Example.COM."},
+		{[]string{strictDot}, "NS1.Example.COM", "ns1.example.com"},
+		{[]string{strictCase, strictDot}, "NS1.Example.COM.", "NS1.Example.COM."},
+	}
+
+	for _, tt := range tests {
+		strictNS = make(map[string]bool)
+		if err := parseStrictNS(tt.strict); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := normaliseNS(tt.ns); got != tt.want {
+			t.Errorf("normaliseNS(%q) with --strict-ns %v got %q, want %q", tt.ns, tt.strict, got, tt.want)
+		}
+	}
+	strictNS = make(map[string]bool)
+
+	if err := parseStrictNS([]string{"space"}); err == nil {
+		t.Error("parseStrictNS(space) got no error")
+	}
+}
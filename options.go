@@ -0,0 +1,83 @@
+package main
+
+import "strings"
+
+// checkOptions are the optional checks checkDomain collects data for. They're
+// set from the flags, or sent by a coordinator so its remote workers collect
+// the data its own flags compare, see remoteRequest.
+type checkOptions struct {
+	NSHosts      bool     `json:"ns_hosts,omitempty"`
+	DNSBL        []string `json:"dnsbl,omitempty"`
+	Reachability bool     `json:"reachability,omitempty"`
+	Fingerprint  bool     `json:"fingerprint,omitempty"`
+	Case         bool     `json:"check_0x20,omitempty"`
+	ANY          bool     `json:"probe_any,omitempty"`
+	Propagation  bool     `json:"propagation,omitempty"`
+	Resolvers    []string `json:"resolvers,omitempty"`
+	CompareTypes []uint16 `json:"compare_types,omitempty"`
+	TXTPolicy    bool     `json:"txt_policy,omitempty"`
+	Referral     bool     `json:"referral,omitempty"`
+	Wildcard     bool     `json:"wildcard,omitempty"`
+	SOA          bool     `json:"soa,omitempty"`
+	Size         bool     `json:"size,omitempty"`
+	Transfer     bool     `json:"transfer,omitempty"`
+	Apex         bool     `json:"apex,omitempty"`
+	Expiry       bool     `json:"expiry,omitempty"`
+	Locks        bool     `json:"locks,omitempty"`
+	Registrars   bool     `json:"registrars,omitempty"`
+}
+
+// flagOptions returns the checks enabled by the flags.
+func flagOptions() checkOptions {
+	return checkOptions{
+		NSHosts:      *argsCNAME,
+		DNSBL:        *argsDNSBL,
+		Reachability: *argsReach,
+		Fingerprint:  *argsFingerprint,
+		Case:         *argsCase,
+		ANY:          *argsANY,
+		Propagation:  *argsPropagation,
+		Resolvers:    resolvers(),
+		CompareTypes: compareTypes,
+		TXTPolicy:    *argsTXT,
+		Referral:     *argsReferral,
+		Wildcard:     *argsWildcard,
+		SOA:          *argsSOA,
+		Size:         *argsSize,
+		Transfer:     *argsTransfer,
+		Apex:         *argsApex,
+		Expiry:       *argsExpiry,
+		Locks:        *argsLocks,
+		Registrars:   *argsRegistrars,
+	}
+}
+
+// workerOptions returns the options a coordinator sent with a batch, with its
+// resolvers and DNSBL zones limited to the worker's own, so a worker can't be
+// used to send queries to any server. If none of the coordinator's resolvers
+// are the worker's, the worker's own are used.
+func workerOptions(opts checkOptions) checkOptions {
+	own := flagOptions()
+
+	opts.Resolvers = allowedOnly(opts.Resolvers, own.Resolvers)
+	if len(opts.Resolvers) == 0 {
+		opts.Resolvers = own.Resolvers
+	}
+	opts.DNSBL = allowedOnly(opts.DNSBL, own.DNSBL)
+
+	return opts
+}
+
+// allowedOnly returns the values also in allowed.
+func allowedOnly(values, allowed []string) []string {
+	var filtered []string
+	for _, v := range values {
+		for _, a := range allowed {
+			if strings.EqualFold(v, a) {
+				filtered = append(filtered, v)
+				break
+			}
+		}
+	}
+	return filtered
+}
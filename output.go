@@ -0,0 +1,401 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// auditStats summarises a run, for writers to report once all domains have
+// been checked.
+type auditStats struct {
+	Started           time.Time
+	Domains           int
+	DomainsWithErrors int
+	TotalErrors       int
+	ErrorClasses      map[ErrorClass]int
+	// Registrars breaks down the domains by registrar, with --registrars
+	Registrars map[string]*registrarStats
+	// Failed is each domain with errors or warnings
+	Failed []DomainNS
+}
+
+type registrarStats struct {
+	Domains           int `json:"domains"`
+	DomainsWithErrors int `json:"domains_with_errors"`
+}
+
+// addRegistrar counts the domain against its registrar, domains without an RDAP
+// registrar are counted as unknown.
+func (s *auditStats) addRegistrar(domainNS *DomainNS, errors int) {
+	if !*argsRegistrars {
+		return
+	}
+	if s.Registrars == nil {
+		s.Registrars = make(map[string]*registrarStats)
+	}
+
+	registrar := "unknown"
+	if domainNS.RDAP != nil && domainNS.RDAP.Registrar != "" {
+		registrar = domainNS.RDAP.Registrar
+	}
+
+	r, ok := s.Registrars[registrar]
+	if !ok {
+		r = &registrarStats{}
+		s.Registrars[registrar] = r
+	}
+	r.Domains++
+	if errors > 0 {
+		r.DomainsWithErrors++
+	}
+}
+
+// registrarNames returns the registrars with the most domains first.
+func (s *auditStats) registrarNames() []string {
+	var names []string
+	for name := range s.Registrars {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := s.Registrars[names[i]], s.Registrars[names[j]]
+		if a.Domains != b.Domains {
+			return a.Domains > b.Domains
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// report returns the JSON report of the run with the results, sorted by risk
+// score.
+func (s *auditStats) report(results []jsonDomain) jsonReport {
+	report := jsonReport{
+		SchemaVersion:     schemaVersion,
+		Time:              s.Started,
+		Domains:           s.Domains,
+		DomainsWithErrors: s.DomainsWithErrors,
+		TotalErrors:       s.TotalErrors,
+		ErrorClasses:      make(map[string]int),
+		Registrars:        s.Registrars,
+		Results:           results,
+	}
+	// Riskiest domains first, for triage
+	sort.SliceStable(report.Results, func(i, j int) bool {
+		return report.Results[i].Score > report.Results[j].Score
+	})
+	for class, count := range s.ErrorClasses {
+		report.ErrorClasses[class.String()] = count
+	}
+	return report
+}
+
+// outputWriter writes the results of a run in one format, see --output.
+type outputWriter interface {
+	// Domain is called with each domain as it's checked
+	Domain(domainNS *DomainNS) error
+	// Close is called once all domains have been checked
+	Close(stats *auditStats) error
+}
+
+// Output formats, and the writer for each
+var outputFormats = map[string]func(w io.WriteCloser) outputWriter{
+	"text":   func(w io.WriteCloser) outputWriter { return &textWriter{w: w} },
+	"github": func(w io.WriteCloser) outputWriter { return &githubWriter{w: w} },
+	"json":   func(w io.WriteCloser) outputWriter { return &jsonWriter{w: w} },
+	"html":   func(w io.WriteCloser) outputWriter { return &htmlWriter{jsonWriter{w: w}} },
+	"csv":    func(w io.WriteCloser) outputWriter { return newCSVWriter(w) },
+	"ndjson": func(w io.WriteCloser) outputWriter { return &ndjsonWriter{json.NewEncoder(w), w} },
+}
+
+// nopCloser stops writers closing stdout.
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+// openOutputs creates a writer for each format=path spec, path defaults to -
+// for stdout. With no specs, text is written to stdout.
+func openOutputs(specs []string) ([]outputWriter, error) {
+	if len(specs) == 0 {
+		specs = []string{"text"}
+	}
+
+	var (
+		writers []outputWriter
+		files   []*os.File
+	)
+	fail := func(err error) ([]outputWriter, error) {
+		for _, f := range files {
+			f.Close()
+		}
+		return nil, err
+	}
+
+	for _, spec := range specs {
+		format, path, err := parseOutput(spec)
+		if err != nil {
+			return fail(err)
+		}
+
+		var w io.WriteCloser = nopCloser{os.Stdout}
+		if path != "-" {
+			f, err := os.Create(path)
+			if err != nil {
+				return fail(err)
+			}
+			files = append(files, f)
+			w = f
+		}
+
+		writers = append(writers, outputFormats[format](w))
+	}
+	return writers, nil
+}
+
+// parseOutput splits a format=path spec, checking the format is known.
+func parseOutput(spec string) (format, path string, err error) {
+	parts := strings.SplitN(spec, "=", 2)
+	if _, ok := outputFormats[parts[0]]; !ok {
+		return "", "", errors.New(fmt.Sprintf("Invalid output format %s, expected text, github, json, html, csv or ndjson", parts[0]))
+	}
+
+	path = "-"
+	if len(parts) == 2 && parts[1] != "" {
+		path = parts[1]
+	}
+	return parts[0], path, nil
+}
+
+// textWriter writes each domain's messages and the stats as text.
+type textWriter struct {
+	w       io.WriteCloser
+	started bool
+}
+
+func (t *textWriter) Domain(domainNS *DomainNS) error {
+	if !t.started {
+		fmt.Fprintln(t.w)
+		t.started = true
+	}
+	displayNSMsgs(t.w, domainNS)
+	return nil
+}
+
+func (t *textWriter) Close(stats *auditStats) error {
+	fmt.Fprintf(t.w, "\nStats\n-----\n")
+	fmt.Fprintf(t.w, "Domains: %d\n", stats.Domains)
+	fmt.Fprintf(t.w, "Domains with Errors/Warnings: %d (%.0f%%)\n", stats.DomainsWithErrors, percent(stats.DomainsWithErrors, stats.Domains))
+	fmt.Fprintf(t.w, "Domains without Errors/Warnings: %d (%.0f%%)\n", stats.Domains-stats.DomainsWithErrors, percent(stats.Domains-stats.DomainsWithErrors, stats.Domains))
+	fmt.Fprintf(t.w, "Total Errors: %d\n", stats.TotalErrors)
+	for class := ErrUnknown; class <= ErrInvalidTLD; class++ {
+		if stats.ErrorClasses[class] > 0 {
+			fmt.Fprintf(t.w, "Domains failing with %s: %d\n", class, stats.ErrorClasses[class])
+		}
+	}
+	for _, name := range stats.registrarNames() {
+		r := stats.Registrars[name]
+		fmt.Fprintf(t.w, "Registrar %s: %d domains, %d with Errors/Warnings (%.0f%%)\n", name, r.Domains, r.DomainsWithErrors, percent(r.DomainsWithErrors, r.Domains))
+	}
+	// Failed is sorted by risk score
+	for i, domainNS := range stats.Failed {
+		if i == 10 || domainNS.Score == 0 {
+			break
+		}
+		if i == 0 {
+			fmt.Fprintf(t.w, "Riskiest Domains:\n")
+		}
+		fmt.Fprintf(t.w, "  %s: %d\n", domainNS.Domain, domainNS.Score)
+	}
+	return t.w.Close()
+}
+
+// percent returns n as a percentage of total, or 0 if total is 0, such as
+// for an empty domains file.
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total) * 100
+}
+
+// githubWriter writes GitHub Actions workflow commands and a job summary.
+type githubWriter struct {
+	w io.WriteCloser
+}
+
+func (g *githubWriter) Domain(domainNS *DomainNS) error {
+	displayGitHubMsgs(g.w, domainNS)
+	return nil
+}
+
+func (g *githubWriter) Close(stats *auditStats) error {
+	if err := writeGitHubSummary(stats); err != nil {
+		return err
+	}
+	return g.w.Close()
+}
+
+// jsonWriter writes a JSON report, the same as uploaded by --upload.
+type jsonWriter struct {
+	w       io.WriteCloser
+	results []jsonDomain
+}
+
+func (j *jsonWriter) Domain(domainNS *DomainNS) error {
+	j.results = append(j.results, newJSONDomain(domainNS))
+	return nil
+}
+
+func (j *jsonWriter) Close(stats *auditStats) error {
+	enc := json.NewEncoder(j.w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(stats.report(j.results)); err != nil {
+		j.w.Close()
+		return err
+	}
+	return j.w.Close()
+}
+
+// ndjsonWriter writes each domain's result as a line of JSON as soon as it's
+// checked, for consumers processing results during long runs.
+type ndjsonWriter struct {
+	enc *json.Encoder
+	w   io.WriteCloser
+}
+
+func (n *ndjsonWriter) Domain(domainNS *DomainNS) error {
+	return n.enc.Encode(newJSONDomain(domainNS))
+}
+
+func (n *ndjsonWriter) Close(stats *auditStats) error {
+	return n.w.Close()
+}
+
+// csvWriter writes a row per message, or an OK row for domains without
+// messages, for spreadsheets. Rows are written on Close, sorted by the
+// domain's risk score.
+type csvWriter struct {
+	w       io.WriteCloser
+	domains []DomainNS
+}
+
+func newCSVWriter(w io.WriteCloser) *csvWriter {
+	return &csvWriter{w: w}
+}
+
+func (c *csvWriter) Domain(domainNS *DomainNS) error {
+	c.domains = append(c.domains, *domainNS)
+	return nil
+}
+
+func (c *csvWriter) Close(stats *auditStats) error {
+	sortByScore(c.domains)
+
+	w := csv.NewWriter(c.w)
+	w.Write([]string{"domain", "vantage", "score", "severity", "check", "message", "suppressed"})
+	for _, domainNS := range c.domains {
+		score := strconv.Itoa(domainNS.Score)
+		if len(domainNS.MSGs) == 0 {
+			w.Write([]string{domainNS.Domain, domainNS.Vantage, score, "OK", "", "", ""})
+			continue
+		}
+		for _, msg := range domainNS.MSGs {
+			if msg.pri == LOG_WARNING && !*argsZ {
+				continue
+			}
+			w.Write([]string{domainNS.Domain, domainNS.Vantage, score, priNames[msg.pri], msg.check, msg.msg, msg.suppressed})
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		c.w.Close()
+		return err
+	}
+	return c.w.Close()
+}
+
+// htmlWriter writes a standalone HTML report.
+type htmlWriter struct {
+	jsonWriter
+}
+
+var htmlReport = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>nsaudit {{.Time.Format "2006-01-02 15:04"}}</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 0.2em 0.5em; text-align: left; vertical-align: top; }
+.CRIT, .ERR { color: #b00; }
+.WARN { color: #a60; }
+.SUPPRESSED { color: #888; }
+</style>
+</head>
+<body>
+<h1>nsaudit</h1>
+<p>{{.Time.Format "2006-01-02 15:04:05 MST"}}</p>
+<table>
+<tr><th>Domains</th><th>With Errors/Warnings</th><th>Total Errors</th></tr>
+<tr><td>{{.Domains}}</td><td>{{.DomainsWithErrors}}</td><td>{{.TotalErrors}}</td></tr>
+</table>
+{{if .ErrorClasses}}
+<h2>Failure Types</h2>
+<table>
+<tr><th>Failure Type</th><th>Domains</th></tr>
+{{range $class, $count := .ErrorClasses}}<tr><td>{{$class}}</td><td>{{$count}}</td></tr>
+{{end}}</table>
+{{end}}
+{{if .Registrars}}
+<h2>Registrars</h2>
+<table>
+<tr><th>Registrar</th><th>Domains</th><th>With Errors/Warnings</th></tr>
+{{range $name, $r := .Registrars}}<tr><td>{{$name}}</td><td>{{$r.Domains}}</td><td>{{$r.DomainsWithErrors}}</td></tr>
+{{end}}</table>
+{{end}}
+<h2>Results</h2>
+<table>
+<tr><th>Domain</th><th>Risk Score</th><th>Registrar NS</th><th>Zone NS</th><th>Messages</th></tr>
+{{range .Results}}<tr>
+<td>{{.Domain}}{{if .Vantage}} via {{.Vantage}}{{end}}</td>
+<td>{{.Score}}</td>
+<td>{{range .RegistrarNS}}{{.}}<br>{{end}}</td>
+<td>{{range .ZoneNS}}{{.}}<br>{{end}}</td>
+<td>{{range .Messages}}{{if .Suppressed}}<span class="SUPPRESSED">SUPPRESSED: {{.Message}} ({{.Suppressed}})</span>{{else}}<span class="{{.Severity}}">{{.Severity}}: {{.Message}}</span>{{end}}<br>{{else}}OK{{end}}</td>
+</tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+func (h *htmlWriter) Domain(domainNS *DomainNS) error {
+	// Warnings are only shown with -z, as in the text output
+	shown := *domainNS
+	shown.MSGs = nil
+	for _, msg := range domainNS.MSGs {
+		if msg.pri != LOG_WARNING || *argsZ {
+			shown.MSGs = append(shown.MSGs, msg)
+		}
+	}
+	return h.jsonWriter.Domain(&shown)
+}
+
+func (h *htmlWriter) Close(stats *auditStats) error {
+	if err := htmlReport.Execute(h.w, stats.report(h.results)); err != nil {
+		h.w.Close()
+		return err
+	}
+	return h.w.Close()
+}
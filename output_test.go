@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOpenOutputs(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		specs   []string
+		writers int
+		wantErr bool
+	}{
+		{specs: nil, writers: 1},
+		{specs: []string{"text", "json=" + filepath.Join(dir, "out.json"), "csv=-"}, writers: 3},
+		{specs: []string{"json=" + filepath.Join(dir, "out.json"), "pdf=out.pdf"}, wantErr: true},
+		{specs: []string{"json=" + filepath.Join(dir, "out.json"), "csv=" + filepath.Join(dir, "missing", "out.csv")}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		writers, err := openOutputs(tt.specs)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("openOutputs(%q) error %v, want error %t", tt.specs, err, tt.wantErr)
+			continue
+		}
+		if len(writers) != tt.writers {
+			t.Errorf("openOutputs(%q) got %d writers, want %d", tt.specs, len(writers), tt.writers)
+		}
+	}
+}
+
+func TestTextWriterNoDomains(t *testing.T) {
+	var buf bytes.Buffer
+	w := &textWriter{w: nopCloser{&buf}}
+	if err := w.Close(&auditStats{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(buf.String(), "NaN") {
+		t.Errorf("stats for no domains contain NaN:\n%s", buf.String())
+	}
+	if !strings.Contains(buf.String(), "Domains with Errors/Warnings: 0 (0%)") {
+		t.Errorf("stats for no domains missing 0%%:\n%s", buf.String())
+	}
+}
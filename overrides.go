@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// domainSettings are the timeouts, retries and checks used for a domain, the
+// global options unless overridden, see --overrides.
+type domainSettings struct {
+	timeout  time.Duration
+	retries  int
+	deadline time.Duration
+	skip     map[string]bool // checks not run for the domain
+}
+
+// enabled returns whether the check should be run for the domain.
+func (s domainSettings) enabled(check string) bool {
+	return !s.skip[check]
+}
+
+// override is a setting changed for a domain and the domains under it.
+type override struct {
+	suffix  string
+	setting string
+	value   string
+}
+
+// overrides sorted from the least to most specific suffix, so more specific
+// overrides are applied last.
+var overrides []override
+
+// loadOverrides reads the overrides file, a CSV file of domain or TLD, setting
+// and value. Settings are timeout, retry, domain-deadline and skip, which
+// names a check not to run and can be given more than once. Lines starting
+// with # are ignored.
+func loadOverrides(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.FieldsPerRecord = 3
+	r.TrimLeadingSpace = true
+
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		o := override{suffix: normaliseDomain(record[0]), setting: record[1], value: record[2]}
+		if o.suffix == "" {
+			return errors.New(fmt.Sprintf("No domain or TLD for %s override", o.setting))
+		}
+
+		// Apply to a zero value to validate the override up front
+		if err := o.apply(&domainSettings{skip: make(map[string]bool)}); err != nil {
+			return errors.New(fmt.Sprintf("%s for %s", err, record[0]))
+		}
+
+		overrides = append(overrides, o)
+	}
+
+	sort.SliceStable(overrides, func(i, j int) bool {
+		return strings.Count(overrides[i].suffix, ".") < strings.Count(overrides[j].suffix, ".")
+	})
+
+	return nil
+}
+
+// apply changes the settings by the override.
+func (o override) apply(s *domainSettings) (err error) {
+	switch o.setting {
+	case "timeout":
+		s.timeout, err = time.ParseDuration(o.value)
+		if err != nil || s.timeout <= 0 {
+			return errors.New(fmt.Sprintf("Invalid timeout %s, expected a duration such as 10s", o.value))
+		}
+	case "retry":
+		s.retries, err = strconv.Atoi(o.value)
+		if err != nil || s.retries < 1 {
+			return errors.New(fmt.Sprintf("Invalid retry %s, expected a number of at least 1", o.value))
+		}
+	case "domain-deadline":
+		s.deadline, err = time.ParseDuration(o.value)
+		if err != nil || s.deadline <= 0 {
+			return errors.New(fmt.Sprintf("Invalid domain-deadline %s, expected a duration such as 1m", o.value))
+		}
+	case "skip":
+		if !validCheck(o.value) || o.value == checkError {
+			return errors.New(fmt.Sprintf("Unknown check %s to skip", o.value))
+		}
+		s.skip[o.value] = true
+	default:
+		return errors.New(fmt.Sprintf("Unknown setting %s, expected timeout, retry, domain-deadline or skip", o.setting))
+	}
+	return nil
+}
+
+// settingsFor returns the domain's settings, the global options changed by
+// each override for the domain or a parent of it.
+func settingsFor(domain string) domainSettings {
+	s := domainSettings{
+		timeout:  time.Duration(*argsTO) * time.Second,
+		retries:  *argsRE,
+		deadline: domainDeadline,
+		skip:     make(map[string]bool),
+	}
+
+	domain = normaliseDomain(domain)
+	for _, o := range overrides {
+		if domain == o.suffix || strings.HasSuffix(domain, "."+o.suffix) {
+			// Validated when loaded
+			o.apply(&s)
+		}
+	}
+
+	return s
+}
+
+type settingsKey struct{}
+
+// withSettings returns a copy of ctx carrying the domain's settings, used by
+// queries sent with it.
+func withSettings(ctx context.Context, s domainSettings) context.Context {
+	return context.WithValue(ctx, settingsKey{}, s)
+}
+
+// settingsFrom returns the settings carried by ctx, or the global options.
+func settingsFrom(ctx context.Context) domainSettings {
+	if s, ok := ctx.Value(settingsKey{}).(domainSettings); ok {
+		return s
+	}
+	return settingsFor("")
+}
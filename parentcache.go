@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// parentCache caches the name server to query for each parent zone, along
+// with failed lookups so a bad parent isn't looked up again for every domain
+// under it.
+type parentCache struct {
+	mu      sync.Mutex
+	entries map[string]parentCacheEntry
+}
+
+type parentCacheEntry struct {
+	ns  string
+	err error
+	// expires is when a failed lookup should be retried, successful lookups
+	// don't expire
+	expires time.Time
+}
+
+func newParentCache() *parentCache {
+	return &parentCache{entries: make(map[string]parentCacheEntry)}
+}
+
+// key returns the cache key for the parent zone, so differences in case and
+// trailing dots share an entry.
+func (c *parentCache) key(zone string) string {
+	return strings.ToLower(strings.TrimRight(zone, ".")) + "."
+}
+
+// Get returns the cached entry for zone, ok is false if there's no entry or
+// a failed lookup has expired.
+func (c *parentCache) Get(zone string) (entry parentCacheEntry, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok = c.entries[c.key(zone)]
+	if ok && entry.err != nil && time.Now().After(entry.expires) {
+		delete(c.entries, c.key(zone))
+		return parentCacheEntry{}, false
+	}
+	return
+}
+
+// Set caches the name server for zone.
+func (c *parentCache) Set(zone, ns string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[c.key(zone)] = parentCacheEntry{ns: ns}
+}
+
+// SetError caches a failed lookup for zone until ttl passes.
+func (c *parentCache) SetError(zone string, err error, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[c.key(zone)] = parentCacheEntry{err: err, expires: time.Now().Add(ttl)}
+}
+
+// registryZone returns the zone the domain is registered in, its public suffix
+// from the public suffix list, eg co.uk. for example.co.uk. and
+// www.example.co.uk., or blogspot.com. for foo.blogspot.com., so every domain
+// registered under it shares a cache entry. Public suffixes themselves are
+// registered in their immediate parent.
+func registryZone(domain string) string {
+	name := strings.ToLower(strings.TrimRight(domain, "."))
+	i := strings.Index(name, ".")
+	if i < 0 {
+		return "."
+	}
+
+	suffix, _ := publicsuffix.PublicSuffix(name)
+	if suffix == name {
+		return name[i+1:] + "."
+	}
+	return suffix + "."
+}
@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestRegistryZone(t *testing.T) {
+	tests := []struct {
+		domain string
+		want   string
+	}{
+		{"example.com.", "com."},
+		{"Example.COM", "com."},
+		{"a.b.c.example.com.", "com."},
+		{"example.co.uk.", "co.uk."},
+		{"www.example.co.uk.", "co.uk."},
+		{"example.com.au.", "com.au."},
+		{"www.example.com.au.", "com.au."},
+		{"foo.blogspot.com.", "blogspot.com."},
+		{"x.github.io.", "github.io."},
+		{"a.x.github.io.", "github.io."},
+		{"co.uk.", "uk."},
+		{"com.au.", "au."},
+		{"github.io.", "io."},
+		{"com.", "."},
+		{"example.notatld.", "notatld."},
+	}
+
+	for _, tt := range tests {
+		if got := registryZone(tt.domain); got != tt.want {
+			t.Errorf("registryZone(%q) got %q, want %q", tt.domain, got, tt.want)
+		}
+	}
+}
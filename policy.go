@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/deckarep/golang-set"
+	"github.com/miekg/dns"
+)
+
+// Label prefixed to a domain to find its TXT policy
+const policyLabel = "_nsaudit"
+
+// queryPolicy fetches the domain's TXT policy from the zone's name server,
+// returning a nil set if the domain doesn't publish one, and each name as
+// given in the policy.
+//
+// The policy is a list of ns=<host> entries separated by semicolons, eg:
+// _nsaudit.example.com TXT "ns=ns1.example.net.;ns=ns2.example.net."
+func queryPolicy(ctx context.Context, domain, nameServer string) (set mapset.Set, raw []string, err error) {
+	name := policyLabel + "." + domain
+
+	r, err := query(ctx, name, nameServer, dns.TypeTXT)
+	if err != nil {
+		return
+	}
+
+	if r.Rcode == dns.RcodeNameError {
+		return nil, nil, nil
+	}
+	if r.Rcode != dns.RcodeSuccess {
+		err = newDomainError(rcodeClass(r.Rcode), "Bad response for TXT policy:%s", name)
+		return
+	}
+
+	for _, a := range r.Answer {
+		txt, ok := a.(*dns.TXT)
+		if !ok {
+			continue
+		}
+		for _, entry := range strings.Split(strings.Join(txt.Txt, ""), ";") {
+			entry = strings.TrimSpace(entry)
+			if !strings.HasPrefix(entry, "ns=") {
+				continue
+			}
+			if set == nil {
+				set = mapset.NewSet()
+			}
+			set.Add(normaliseNS(entry[len("ns="):]))
+			raw = append(raw, entry[len("ns="):])
+		}
+	}
+
+	return
+}
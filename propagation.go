@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// Public resolvers queried by --propagation and --compare-type unless
+// --resolver is set
+var defaultResolvers = []string{"1.1.1.1", "8.8.8.8", "9.9.9.9"}
+
+// Record types set with --compare-type
+var compareTypes []uint16
+
+// resolverAnswer is the records a public resolver returned for a domain.
+type resolverAnswer struct {
+	Resolver string   `json:"resolver"`
+	Type     string   `json:"type"`
+	Records  []string `json:"records,omitempty"`
+	Error    string   `json:"error,omitempty"`
+	// ErrorClass is compared rather than Error, which names the resolver
+	ErrorClass string `json:"error_class,omitempty"`
+}
+
+// resolvers returns the resolvers to query.
+func resolvers() []string {
+	if len(*argsResolvers) > 0 {
+		return *argsResolvers
+	}
+	return defaultResolvers
+}
+
+// queryResolver asks a recursive resolver for the domain's records of qtype,
+// which may be served from its cache. Records are returned in presentation
+// format without their header, so answers from different caches compare
+// equal regardless of their remaining TTL. The domain's settings carried by ctx
+// set the retries.
+func queryResolver(ctx context.Context, domain, resolverAddr string, qtype uint16) ([]string, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(domain, qtype)
+	m.RecursionDesired = true
+
+	var (
+		r   *dns.Msg
+		err error
+	)
+	for i := 1; i <= settingsFrom(ctx).retries; i++ {
+		r, err = exchangeContext(ctx, m, net.JoinHostPort(resolverAddr, "53"))
+		if err == nil || ctx.Err() != nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Rcode != dns.RcodeSuccess {
+		return nil, newDomainError(rcodeClass(r.Rcode), "Bad response for %s records for domain:%s", dns.TypeToString[qtype], domain)
+	}
+
+	var records []string
+	for _, a := range r.Answer {
+		if a.Header().Rrtype != qtype {
+			continue
+		}
+		if ns, ok := a.(*dns.NS); ok {
+			records = append(records, normaliseNS(ns.Ns))
+			continue
+		}
+		records = append(records, rdata(a))
+	}
+	sort.Strings(records)
+	return records, nil
+}
+
+// rdata returns the record in presentation format without its header.
+func rdata(rr dns.RR) string {
+	return strings.TrimSpace(strings.TrimPrefix(rr.String(), rr.Header().String()))
+}
+
+// lookupResolvers queries each resolver for the domain's records of each
+// type.
+func lookupResolvers(ctx context.Context, domain string, addrs []string, qtypes []uint16) (answers []resolverAnswer) {
+	for _, qtype := range qtypes {
+		for _, addr := range addrs {
+			log.Printf("Fetching %s records for domain %s from resolver %s", dns.TypeToString[qtype], domain, addr)
+			answer := resolverAnswer{Resolver: addr, Type: dns.TypeToString[qtype]}
+			records, err := queryResolver(ctx, domain, addr, qtype)
+			if err != nil {
+				answer.Error = err.Error()
+				answer.ErrorClass = classifyError(err).Class.String()
+			}
+			answer.Records = records
+			answers = append(answers, answer)
+		}
+	}
+	return
+}
+
+// comparePropagation reports resolvers whose NS records differ from the
+// registrar's, such as stale delegations cached from before a migration.
+func comparePropagation(domainNS *DomainNS) (errors int) {
+
+	for _, answer := range domainNS.Propagation {
+		if answer.Error != "" {
+			domainNS.MSGs = append(domainNS.MSGs, msg{pri: LOG_ERR, check: checkPropagation, msg: fmt.Sprintf("Resolver %s failed: %s", answer.Resolver, answer.Error)})
+			errors++
+			continue
+		}
+
+		if !setFromStrings(answer.Records).Equal(domainNS.RegistrarNS) {
+			domainNS.MSGs = append(domainNS.MSGs, msg{pri: LOG_ERR, check: checkPropagation, msg: fmt.Sprintf("Resolver %s serving stale NS records: %v", answer.Resolver, answer.Records)})
+			errors++
+		}
+	}
+
+	return
+}
+
+// compareAnswers reports record types the resolvers gave different answers
+// for, which may be a split brain or a poisoned cache.
+func compareAnswers(domainNS *DomainNS) (errors int) {
+
+	byType := make(map[string][]resolverAnswer)
+	var types []string
+	for _, answer := range domainNS.Answers {
+		if _, ok := byType[answer.Type]; !ok {
+			types = append(types, answer.Type)
+		}
+		byType[answer.Type] = append(byType[answer.Type], answer)
+	}
+
+	for _, qtype := range types {
+		answers := byType[qtype]
+
+		divergent := false
+		for _, answer := range answers[1:] {
+			if answer.ErrorClass != answers[0].ErrorClass || strings.Join(answer.Records, " ") != strings.Join(answers[0].Records, " ") {
+				divergent = true
+			}
+		}
+		if !divergent {
+			continue
+		}
+
+		var got []string
+		for _, answer := range answers {
+			if answer.Error != "" {
+				got = append(got, fmt.Sprintf("%s: %s", answer.Resolver, answer.Error))
+			} else {
+				got = append(got, fmt.Sprintf("%s: %v", answer.Resolver, answer.Records))
+			}
+		}
+		domainNS.MSGs = append(domainNS.MSGs, msg{pri: LOG_ERR, check: checkAnswers, msg: fmt.Sprintf("Resolvers disagree on %s records, %s", qtype, strings.Join(got, ", "))})
+		errors++
+	}
+
+	return
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IANA's bootstrap registry mapping TLDs to their RDAP servers, see RFC 7484
+const rdapBootstrapURL = "https://data.iana.org/rdap/dns.json"
+
+// Statuses a registrar lock should set, in EPP form
+var requiredLocks = []string{"clientTransferProhibited", "clientDeleteProhibited"}
+
+// The bootstrap registry is fetched again after this long, so long running
+// processes see TLDs' RDAP servers change
+const rdapRefresh = 24 * time.Hour
+
+var (
+	rdapMu      sync.Mutex
+	rdapClient  *http.Client
+	rdapServers map[string]string
+	// rdapLoaded is when rdapServers was fetched, rdapFailed when rdapErr
+	// was last returned fetching it, which is retried after
+	// --negative-cache-ttl
+	rdapLoaded time.Time
+	rdapFailed time.Time
+	rdapErr    error
+)
+
+type rdapBootstrap struct {
+	Services [][][]string `json:"services"`
+}
+
+type rdapDomain struct {
+	Status []string    `json:"status"`
+	Events []rdapEvent `json:"events"`
+	// Registrar is the name of the registrar entity, if published
+	Registrar string `json:"registrar,omitempty"`
+}
+
+// rdapEntity is a contact in an RDAP response, only what's needed to find the
+// registrar's name.
+type rdapEntity struct {
+	Roles      []string      `json:"roles"`
+	Handle     string        `json:"handle"`
+	VCardArray []interface{} `json:"vcardArray"`
+}
+
+// name returns the entity's formatted name from its jCard, see RFC 7095,
+// falling back to its handle.
+func (e rdapEntity) name() string {
+	if len(e.VCardArray) == 2 {
+		props, _ := e.VCardArray[1].([]interface{})
+		for _, p := range props {
+			prop, ok := p.([]interface{})
+			if !ok || len(prop) < 4 || prop[0] != "fn" {
+				continue
+			}
+			if fn, ok := prop[3].(string); ok && fn != "" {
+				return fn
+			}
+		}
+	}
+	return e.Handle
+}
+
+type rdapEvent struct {
+	Action string    `json:"eventAction"`
+	Date   time.Time `json:"eventDate"`
+}
+
+// Expiry returns the domain's expiration event, or the zero time if the
+// registry didn't publish one.
+func (d *rdapDomain) Expiry() time.Time {
+	for _, event := range d.Events {
+		if event.Action == "expiration" {
+			return event.Date
+		}
+	}
+	return time.Time{}
+}
+
+// HasStatus reports whether the domain has the EPP status, RDAP's
+// "client transfer prohibited" form is also accepted, see RFC 8056.
+func (d *rdapDomain) HasStatus(status string) bool {
+	for _, s := range d.Status {
+		if strings.EqualFold(strings.Replace(s, " ", "", -1), status) {
+			return true
+		}
+	}
+	return false
+}
+
+func rdapGet(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/rdap+json")
+
+	resp, err := rdapClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(fmt.Sprintf("Bad RDAP response from %s: %s", url, resp.Status))
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// loadRDAPServers returns the TLDs' RDAP servers from the bootstrap registry,
+// which every domain shares, fetching it on first use and once it's older than
+// rdapRefresh. A failed fetch is retried after --negative-cache-ttl, the
+// previous registry, if any, is used until then.
+func loadRDAPServers() (map[string]string, error) {
+	rdapMu.Lock()
+	defer rdapMu.Unlock()
+
+	if rdapClient == nil {
+		rdapClient = &http.Client{
+			Timeout:   time.Duration(*argsTO) * time.Second,
+			Transport: &http.Transport{DialContext: dialTCP, Proxy: http.ProxyFromEnvironment},
+		}
+	}
+
+	if rdapServers != nil && time.Since(rdapLoaded) < rdapRefresh {
+		return rdapServers, nil
+	}
+	if rdapErr != nil && time.Since(rdapFailed) < time.Duration(*argsNegTTL)*time.Second {
+		if rdapServers != nil {
+			return rdapServers, nil
+		}
+		return nil, rdapErr
+	}
+
+	log.Println("Fetching RDAP bootstrap registry")
+
+	var bootstrap rdapBootstrap
+	if err := rdapGet(context.Background(), rdapBootstrapURL, &bootstrap); err != nil {
+		rdapErr, rdapFailed = err, time.Now()
+		if rdapServers != nil {
+			log.Println("Error refreshing RDAP bootstrap registry, using the previous one:", err)
+			return rdapServers, nil
+		}
+		return nil, err
+	}
+
+	// Each service is a pair of TLDs and the URLs serving them
+	servers := make(map[string]string)
+	for _, service := range bootstrap.Services {
+		if len(service) != 2 || len(service[1]) == 0 {
+			continue
+		}
+		for _, tld := range service[0] {
+			servers[strings.ToLower(tld)] = service[1][0]
+		}
+	}
+
+	rdapServers, rdapLoaded, rdapErr = servers, time.Now(), nil
+	return servers, nil
+}
+
+func queryRDAP(ctx context.Context, domain string) (*rdapDomain, error) {
+	servers, err := loadRDAPServers()
+	if err != nil {
+		return nil, err
+	}
+
+	domain = strings.ToLower(strings.TrimRight(domain, "."))
+	tld := domain[strings.LastIndex(domain, ".")+1:]
+
+	server, ok := servers[tld]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("No RDAP server for tld %s", tld))
+	}
+
+	var rdap struct {
+		rdapDomain
+		Entities []rdapEntity `json:"entities"`
+	}
+	if err := rdapGet(ctx, strings.TrimRight(server, "/")+"/domain/"+domain, &rdap); err != nil {
+		return nil, err
+	}
+
+	for _, entity := range rdap.Entities {
+		for _, role := range entity.Roles {
+			if role == "registrar" {
+				rdap.Registrar = entity.name()
+			}
+		}
+	}
+
+	return &rdap.rdapDomain, nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// reachability is whether a name server answered over one transport.
+type reachability struct {
+	// Transport is one of udp4, udp6, tcp4 or tcp6
+	Transport string `json:"transport"`
+	// Status is ok, fail, or none if the name server has no address of the
+	// transport's family
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms,omitempty"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// reachEntry is a cached reachability matrix, matrices with a failed
+// transport expire so they're retried.
+type reachEntry struct {
+	matrix  []reachability
+	expires time.Time
+}
+
+var (
+	reachMu    sync.Mutex
+	reachCache = make(map[string]reachEntry)
+)
+
+// resetReachCache forgets the cached matrices, see resetHostCaches.
+func resetReachCache() {
+	reachMu.Lock()
+	reachCache = make(map[string]reachEntry)
+	reachMu.Unlock()
+}
+
+// checkReachability queries the first IPv4 and IPv6 address of the name
+// server over UDP and TCP. Reachability doesn't depend on the domain, so
+// results, measured with the first domain checked, are cached per name server
+// for the run, and failures for --negative-cache-ttl.
+func checkReachability(ctx context.Context, host string, h nsHost, domain string) []reachability {
+	reachMu.Lock()
+	cached, ok := reachCache[host]
+	reachMu.Unlock()
+	if ok && (cached.expires.IsZero() || time.Now().Before(cached.expires)) {
+		return cached.matrix
+	}
+
+	var ip4, ip6 net.IP
+	for _, ip := range h.Addrs {
+		if ip.To4() != nil && ip4 == nil {
+			ip4 = ip
+		}
+		if ip.To4() == nil && ip6 == nil {
+			ip6 = ip
+		}
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion(domain, dns.TypeSOA)
+
+	var matrix []reachability
+	for _, network := range []string{"udp", "tcp"} {
+		for _, family := range []struct {
+			suffix string
+			ip     net.IP
+		}{{"4", ip4}, {"6", ip6}} {
+			reach := reachability{Transport: network + family.suffix}
+
+			if family.ip == nil {
+				reach.Status = "none"
+				matrix = append(matrix, reach)
+				continue
+			}
+
+			_, rtt, err := exchangeNetContext(ctx, m, net.JoinHostPort(family.ip.String(), "53"), network)
+			if err != nil {
+				reach.Status = "fail"
+				reach.Error = err.Error()
+			} else {
+				reach.Status = "ok"
+				reach.LatencyMS = float64(rtt) / float64(time.Millisecond)
+			}
+			matrix = append(matrix, reach)
+		}
+	}
+
+	// Queries cut short by the domain's deadline say nothing about the host
+	if ctx.Err() != nil {
+		return matrix
+	}
+
+	entry := reachEntry{matrix: matrix}
+	for _, reach := range matrix {
+		if reach.Status == "fail" {
+			entry.expires = time.Now().Add(time.Duration(*argsNegTTL) * time.Second)
+		}
+	}
+
+	reachMu.Lock()
+	reachCache[host] = entry
+	reachMu.Unlock()
+
+	return matrix
+}
+
+// reachabilityMatrix checks the reachability of each of the domain's name
+// servers.
+func reachabilityMatrix(ctx context.Context, domainNS *DomainNS) map[string][]reachability {
+	matrix := make(map[string][]reachability)
+	for host, h := range domainNS.NSHosts {
+		if h.Error != nil {
+			continue
+		}
+		log.Println("Checking reachability of NS host:", host)
+		matrix[host] = checkReachability(ctx, host, h, domainNS.Domain)
+	}
+	return matrix
+}
+
+func compareReachability(domainNS *DomainNS) (errors int) {
+
+	var hosts []string
+	for host := range domainNS.Reachability {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	for _, host := range hosts {
+		for _, reach := range domainNS.Reachability[host] {
+			if reach.Status == "fail" {
+				domainNS.MSGs = append(domainNS.MSGs, msg{pri: LOG_ERR, check: checkReach, msg: fmt.Sprintf("NS host %s unreachable over %s: %s", host, reach.Transport, reach.Error)})
+				errors++
+			}
+		}
+	}
+
+	return
+}
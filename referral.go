@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// referral is the parent zone's response to an NS query for the domain.
+type referral struct {
+	Authoritative bool `json:"authoritative"`
+	// Answer is any records in the answer section, which a referral
+	// shouldn't have
+	Answer []string `json:"answer,omitempty"`
+	// NS is the NS records in the authority section, as owner and host
+	NS []string `json:"ns,omitempty"`
+	// Glue is the addresses of each host in the additional section
+	Glue map[string][]string `json:"glue,omitempty"`
+}
+
+// queryReferral fetches the referral to the domain from the parent zone's name
+// server.
+func queryReferral(ctx context.Context, domain, parentNS string) (*referral, error) {
+	r, err := query(ctx, domain, parentNS, dns.TypeNS)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Rcode != dns.RcodeSuccess {
+		return nil, newDomainError(rcodeClass(r.Rcode), "Bad response for referral for domain:%s", domain)
+	}
+
+	ref := &referral{Authoritative: r.Authoritative, Glue: make(map[string][]string)}
+	for _, a := range r.Answer {
+		ref.Answer = append(ref.Answer, a.String())
+	}
+	for _, a := range r.Ns {
+		if ns, ok := a.(*dns.NS); ok {
+			ref.NS = append(ref.NS, ns.Hdr.Name+" "+ns.Ns)
+		}
+	}
+	for _, a := range r.Extra {
+		switch rr := a.(type) {
+		case *dns.A:
+			ref.Glue[strings.ToLower(rr.Hdr.Name)] = append(ref.Glue[strings.ToLower(rr.Hdr.Name)], rr.A.String())
+		case *dns.AAAA:
+			ref.Glue[strings.ToLower(rr.Hdr.Name)] = append(ref.Glue[strings.ToLower(rr.Hdr.Name)], rr.AAAA.String())
+		}
+	}
+
+	return ref, nil
+}
+
+// compareReferral reports referrals with answers, NS records for the wrong
+// owner, or missing glue for name servers within the domain.
+func compareReferral(domainNS *DomainNS) (errors int) {
+
+	ref := domainNS.Referral
+	if ref == nil {
+		return
+	}
+	report := func(pri int, format string, a ...interface{}) {
+		domainNS.MSGs = append(domainNS.MSGs, msg{pri: pri, check: checkReferral, msg: fmt.Sprintf(format, a...)})
+		errors++
+	}
+
+	if len(ref.Answer) > 0 {
+		report(LOG_ERR, "Referral has records in the answer section: %v", ref.Answer)
+	}
+
+	if len(ref.NS) == 0 {
+		report(LOG_ERR, "Referral has no NS records in the authority section")
+		return
+	}
+
+	hosts := make(map[string]bool)
+	for _, ns := range ref.NS {
+		parts := strings.SplitN(ns, " ", 2)
+		if !strings.EqualFold(parts[0], domainNS.Domain) {
+			report(LOG_ERR, "Referral NS record for %s, expected %s", parts[0], domainNS.Domain)
+			continue
+		}
+		hosts[strings.ToLower(parts[1])] = true
+	}
+
+	var sorted []string
+	for host := range hosts {
+		sorted = append(sorted, host)
+	}
+	sort.Strings(sorted)
+
+	// Name servers within the domain can't be resolved without glue
+	for _, host := range sorted {
+		if dns.IsSubDomain(strings.ToLower(domainNS.Domain), host) && len(ref.Glue[host]) == 0 {
+			report(LOG_ERR, "Referral missing glue for NS host %s", host)
+		}
+	}
+
+	var glue []string
+	for host := range ref.Glue {
+		glue = append(glue, host)
+	}
+	sort.Strings(glue)
+
+	for _, host := range glue {
+		if !hosts[host] {
+			report(LOG_WARNING, "Referral has glue for %s, which isn't one of the domain's name servers", host)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deckarep/golang-set"
+)
+
+// Domains sent to a remote worker per request
+const remoteBatchSize = 100
+
+type remoteRequest struct {
+	Domains []string `json:"domains"`
+	// Options are the coordinator's checks, so the worker collects the data
+	// they compare, nil uses the worker's own flags
+	Options *checkOptions `json:"options,omitempty"`
+}
+
+type remoteResponse struct {
+	SchemaVersion int          `json:"schema_version"`
+	Results       []jsonDomain `json:"results"`
+}
+
+// serveWorker checks domains sent by a coordinator, see checkRemote, and runs
+// batch audit jobs, see jobServer.
+func serveWorker(addr string, requiredNS, allowedAddrs mapset.Set) error {
+	http.HandleFunc("/check", handleCheck)
+	http.HandleFunc("/schema", handleSchema)
+	jobs := newJobServer(requiredNS, allowedAddrs)
+	http.Handle("/jobs", jobs)
+	http.Handle("/jobs/", jobs)
+	log.Println("Listening for requests on:", addr)
+	return http.ListenAndServe(addr, nil)
+}
+
+func handleCheck(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if *argsRemoteToken != "" && r.Header.Get("Authorization") != "Bearer "+*argsRemoteToken {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req remoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("Checking %d domains for %s", len(req.Domains), r.RemoteAddr)
+	resetHostCaches()
+
+	opts := flagOptions()
+	if req.Options != nil {
+		opts = workerOptions(*req.Options)
+	}
+
+	inChan := make(chan string, len(req.Domains))
+	for _, domain := range req.Domains {
+		inChan <- domain
+	}
+	close(inChan)
+
+	resp := remoteResponse{SchemaVersion: schemaVersion}
+	for domainNS := range checkQueue(inChan, opts) {
+		resp.Results = append(resp.Results, newJSONDomain(&domainNS))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Println("Error writing response:", err)
+	}
+}
+
+// loopbackAddr reports whether the listen address only accepts connections
+// from this host, eg 127.0.0.1:8053 or localhost:8053.
+func loopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func handleSchema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.Write(schema)
+}
+
+// checkRemote shards the domains across the remote workers, or with
+// --remote-all sends every domain to every worker to check from each vantage
+// point. The channel is closed once all domains have been checked.
+func checkRemote(domains io.Reader) chan DomainNS {
+
+	outChan := make(chan DomainNS, *argsCB)
+
+	var all []string
+	err := readDomains(domains, func(domain string) {
+		all = append(all, domain)
+	})
+	if err != nil {
+		log.Println("Error reading domains:", err)
+	}
+	if *argsShuffle {
+		shuffle(all)
+	}
+
+	var batches [][]string
+	for len(all) > remoteBatchSize {
+		batches = append(batches, all[:remoteBatchSize])
+		all = all[remoteBatchSize:]
+	}
+	if len(all) > 0 {
+		batches = append(batches, all)
+	}
+
+	// Workers pull batches from a shared channel so faster workers take more,
+	// unless every worker is checking every batch
+	shared := make(chan []string, len(batches))
+	for _, batch := range batches {
+		shared <- batch
+	}
+	close(shared)
+
+	var wg sync.WaitGroup
+	for _, remote := range *argsRemote {
+		in := shared
+		if *argsRemoteAll {
+			in = make(chan []string, len(batches))
+			for _, batch := range batches {
+				in <- batch
+			}
+			close(in)
+		}
+
+		wg.Add(1)
+		go func(remote string, in chan []string) {
+			defer wg.Done()
+			for batch := range in {
+				log.Printf("Sending %d domains to remote worker %s", len(batch), remote)
+				results, err := postBatch(remote, batch)
+				if err != nil {
+					log.Println("Error from remote worker:", err)
+					for _, domain := range batch {
+						outChan <- DomainNS{Domain: domain, Vantage: remote, Error: newDomainError(ErrUnavailable, "Remote worker %s failed: %s", remote, err)}
+					}
+					continue
+				}
+				for _, domainNS := range results {
+					outChan <- domainNS
+				}
+			}
+		}(remote, in)
+	}
+
+	go func() {
+		wg.Wait()
+		close(outChan)
+	}()
+
+	return outChan
+}
+
+// batchTimeout is how long a remote worker has to check a batch of n domains,
+// each allowed --domain-deadline, or --timeout for each of its --retry tries.
+func batchTimeout(n int) time.Duration {
+	perDomain := domainDeadline
+	if perDomain == 0 {
+		perDomain = time.Duration(*argsTO) * time.Second * time.Duration(*argsRE)
+	}
+	return perDomain * time.Duration(n)
+}
+
+func postBatch(remote string, batch []string) ([]DomainNS, error) {
+	u, err := url.Parse(remote)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = strings.TrimRight(u.Path, "/") + "/check"
+
+	opts := flagOptions()
+	body, err := json.Marshal(remoteRequest{Domains: batch, Options: &opts})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if *argsRemoteToken != "" {
+		req.Header.Set("Authorization", "Bearer "+*argsRemoteToken)
+	}
+
+	resp, err := (&http.Client{Timeout: batchTimeout(len(batch))}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(fmt.Sprintf("Bad response from %s: %s", u, resp.Status))
+	}
+
+	var rr remoteResponse
+	if err := json.NewDecoder(resp.Body).Decode(&rr); err != nil {
+		return nil, err
+	}
+
+	if rr.SchemaVersion != schemaVersion {
+		return nil, errors.New(fmt.Sprintf("Remote worker %s uses schema version %d, expected %d", remote, rr.SchemaVersion, schemaVersion))
+	}
+
+	var results []DomainNS
+	for _, j := range rr.Results {
+		domainNS := j.DomainNS()
+		domainNS.Vantage = remote
+		results = append(results, domainNS)
+	}
+	return results, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLoopbackAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"127.0.0.1:8053", true},
+		{"[::1]:8053", true},
+		{"localhost:8053", true},
+		{":8053", false},
+		{"0.0.0.0:8053", false},
+		{"192.0.2.1:8053", false},
+		{"worker1:8053", false},
+		{"127.0.0.1", false},
+	}
+
+	for _, tt := range tests {
+		if got := loopbackAddr(tt.addr); got != tt.want {
+			t.Errorf("loopbackAddr(%q) got %t, want %t", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestWorkerOptions(t *testing.T) {
+	defer func(resolvers, dnsbl []string) {
+		*argsResolvers, *argsDNSBL = resolvers, dnsbl
+	}(*argsResolvers, *argsDNSBL)
+	*argsResolvers = []string{"192.0.2.53", "192.0.2.54"}
+	*argsDNSBL = []string{"zen.spamhaus.org"}
+
+	tests := []struct {
+		opts          checkOptions
+		wantResolvers []string
+		wantDNSBL     []string
+	}{
+		{
+			opts:          checkOptions{Resolvers: []string{"192.0.2.54"}, DNSBL: []string{"ZEN.spamhaus.org"}},
+			wantResolvers: []string{"192.0.2.54"},
+			wantDNSBL:     []string{"ZEN.spamhaus.org"},
+		},
+		{
+			opts:          checkOptions{Resolvers: []string{"198.51.100.1"}, DNSBL: []string{"dnsbl.example.com"}},
+			wantResolvers: []string{"192.0.2.53", "192.0.2.54"},
+		},
+	}
+
+	for _, tt := range tests {
+		got := workerOptions(tt.opts)
+		if !reflect.DeepEqual(got.Resolvers, tt.wantResolvers) {
+			t.Errorf("workerOptions(%+v) resolvers %v, want %v", tt.opts, got.Resolvers, tt.wantResolvers)
+		}
+		if !reflect.DeepEqual(got.DNSBL, tt.wantDNSBL) {
+			t.Errorf("workerOptions(%+v) DNSBL %v, want %v", tt.opts, got.DNSBL, tt.wantDNSBL)
+		}
+	}
+}
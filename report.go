@@ -0,0 +1,254 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// trend summarises the stored JSON reports of past runs.
+type trend struct {
+	Since   time.Time   `json:"since"`
+	Runs    []trendRun  `json:"runs"`
+	Flapped []trendFlap `json:"flapped,omitempty"`
+	Fixes   int         `json:"fixes"`
+	// MeanTimeToFix is the mean time between a domain first failing and the
+	// first run it passed again, in seconds
+	MeanTimeToFix float64 `json:"mean_time_to_fix,omitempty"`
+}
+
+type trendRun struct {
+	Time              time.Time `json:"time"`
+	Domains           int       `json:"domains"`
+	DomainsWithErrors int       `json:"domains_with_errors"`
+	ErrorRate         float64   `json:"error_rate"`
+}
+
+// trendFlap is a domain that started failing more than once.
+type trendFlap struct {
+	Domain   string `json:"domain"`
+	Failures int    `json:"failures"`
+}
+
+// parseSince parses a duration, also accepting days such as 30d.
+func parseSince(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil {
+			return 0, errors.New(fmt.Sprintf("Invalid duration %s", s))
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// failing reports whether the domain had an error, or a message counted as an
+// error when the report was written.
+func failing(j jsonDomain) bool {
+	if j.Error != nil {
+		return true
+	}
+	for _, m := range j.Messages {
+		if m.Severity == priNames[LOG_ERR] || m.Severity == priNames[LOG_CRIT] {
+			return true
+		}
+	}
+	return false
+}
+
+// buildTrend summarises the reports written since, in order of their time.
+// Directories are searched for *.json reports.
+func buildTrend(paths []string, since time.Time) (*trend, error) {
+
+	var files []string
+	for _, path := range paths {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !fi.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(path, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+
+	var reports []*jsonReport
+	for _, path := range files {
+		report, err := readReport(path)
+		if err != nil {
+			return nil, err
+		}
+		if report.Time.Before(since) {
+			continue
+		}
+		reports = append(reports, report)
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Time.Before(reports[j].Time) })
+
+	t := &trend{Since: since}
+
+	// When each failing domain started failing, and how many times
+	failingSince := make(map[string]time.Time)
+	failures := make(map[string]int)
+	var fixTime time.Duration
+
+	for _, report := range reports {
+		run := trendRun{Time: report.Time, Domains: report.Domains, DomainsWithErrors: report.DomainsWithErrors}
+		if report.Domains > 0 {
+			run.ErrorRate = float64(report.DomainsWithErrors) / float64(report.Domains)
+		}
+		t.Runs = append(t.Runs, run)
+
+		for _, j := range report.Results {
+			started, wasFailing := failingSince[j.Domain]
+			switch {
+			case failing(j) && !wasFailing:
+				failingSince[j.Domain] = report.Time
+				failures[j.Domain]++
+			case !failing(j) && wasFailing:
+				delete(failingSince, j.Domain)
+				fixTime += report.Time.Sub(started)
+				t.Fixes++
+			}
+		}
+	}
+
+	if t.Fixes > 0 {
+		t.MeanTimeToFix = (fixTime / time.Duration(t.Fixes)).Seconds()
+	}
+
+	for domain, n := range failures {
+		if n > 1 {
+			t.Flapped = append(t.Flapped, trendFlap{Domain: domain, Failures: n})
+		}
+	}
+	sort.Slice(t.Flapped, func(i, j int) bool {
+		if t.Flapped[i].Failures != t.Flapped[j].Failures {
+			return t.Flapped[i].Failures > t.Flapped[j].Failures
+		}
+		return t.Flapped[i].Domain < t.Flapped[j].Domain
+	})
+
+	return t, nil
+}
+
+// runReport writes the trend of the reports to each --output, as text, json or
+// html.
+func runReport(paths []string) error {
+
+	since := time.Time{}
+	if *argsSince != "" {
+		d, err := parseSince(*argsSince)
+		if err != nil {
+			return err
+		}
+		since = time.Now().Add(-d)
+	}
+
+	t, err := buildTrend(paths, since)
+	if err != nil {
+		return err
+	}
+
+	specs := *argsO
+	if len(specs) == 0 {
+		specs = []string{"text"}
+	}
+
+	for _, spec := range specs {
+		parts := strings.SplitN(spec, "=", 2)
+
+		var w io.WriteCloser = nopCloser{os.Stdout}
+		if len(parts) == 2 && parts[1] != "-" {
+			f, err := os.Create(parts[1])
+			if err != nil {
+				return err
+			}
+			w = f
+		}
+
+		switch parts[0] {
+		case "text":
+			err = writeTrendText(w, t)
+		case "json":
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			err = enc.Encode(t)
+		case "html":
+			err = htmlTrend.Execute(w, t)
+		default:
+			err = errors.New(fmt.Sprintf("Invalid output format %s for report, expected text, json or html", parts[0]))
+		}
+		w.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeTrendText(w io.Writer, t *trend) error {
+	fmt.Fprintf(w, "Runs\n----\n")
+	for _, run := range t.Runs {
+		fmt.Fprintf(w, "%s %d/%d domains with errors (%.0f%%)\n", run.Time.Format("2006-01-02 15:04"), run.DomainsWithErrors, run.Domains, run.ErrorRate*100)
+	}
+
+	fmt.Fprintf(w, "\nFlapped\n-------\n")
+	if len(t.Flapped) == 0 {
+		fmt.Fprintln(w, "None")
+	}
+	for _, flap := range t.Flapped {
+		fmt.Fprintf(w, "%s failed %d times\n", flap.Domain, flap.Failures)
+	}
+
+	fmt.Fprintf(w, "\nFixes: %d\n", t.Fixes)
+	if t.Fixes > 0 {
+		fmt.Fprintf(w, "Mean time to fix: %s\n", (time.Duration(t.MeanTimeToFix) * time.Second).Round(time.Minute))
+	}
+	return nil
+}
+
+var htmlTrend = template.Must(template.New("trend").Funcs(template.FuncMap{
+	"mul100": func(f float64) float64 { return f * 100 },
+	"hours":  func(seconds float64) float64 { return seconds / 3600 },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>nsaudit trend</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 0.2em 0.5em; text-align: left; }
+</style>
+</head>
+<body>
+<h1>nsaudit trend</h1>
+<h2>Runs</h2>
+<table>
+<tr><th>Time</th><th>Domains</th><th>With Errors</th><th>Error Rate</th></tr>
+{{range .Runs}}<tr><td>{{.Time.Format "2006-01-02 15:04"}}</td><td>{{.Domains}}</td><td>{{.DomainsWithErrors}}</td><td>{{printf "%.1f%%" (mul100 .ErrorRate)}}</td></tr>
+{{end}}</table>
+<h2>Flapped</h2>
+{{if .Flapped}}<table>
+<tr><th>Domain</th><th>Failures</th></tr>
+{{range .Flapped}}<tr><td>{{.Domain}}</td><td>{{.Failures}}</td></tr>
+{{end}}</table>{{else}}<p>None</p>{{end}}
+<p>Fixes: {{.Fixes}}{{if .Fixes}}, mean time to fix: {{printf "%.1f" (hours .MeanTimeToFix)}} hours{{end}}</p>
+</body>
+</html>
+`))
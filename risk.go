@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// severityWeights are what each message adds to its domain's risk score,
+	// before multiplying by its check's weight
+	severityWeights = map[int]int{LOG_WARNING: 1, LOG_ERR: 5, LOG_CRIT: 10}
+
+	// checkWeights multiply the severity weight of a check's messages,
+	// checks not listed have a weight of 1
+	checkWeights = make(map[string]int)
+)
+
+// parseRiskWeights sets check weights from check=weight pairs, eg dnsbl=3.
+func parseRiskWeights(specs []string) error {
+	for _, spec := range specs {
+		parts := strings.SplitN(spec, "=", 2)
+		if len(parts) != 2 {
+			return errors.New(fmt.Sprintf("Invalid weight %s, expected check=weight", spec))
+		}
+
+		if !validCheck(parts[0]) {
+			return errors.New(fmt.Sprintf("Unknown check %s, expected one of: %s", parts[0], strings.Join(checks, ", ")))
+		}
+
+		weight, err := strconv.Atoi(parts[1])
+		if err != nil || weight < 0 {
+			return errors.New(fmt.Sprintf("Invalid weight %s, must be 0 or more", parts[1]))
+		}
+
+		checkWeights[parts[0]] = weight
+	}
+	return nil
+}
+
+// riskScore returns the sum of the domain's message severities, weighted by
+// check. Informational and suppressed messages don't count.
+func riskScore(domainNS *DomainNS) (score int) {
+	for _, msg := range domainNS.MSGs {
+		weight, ok := checkWeights[msg.check]
+		if !ok {
+			weight = 1
+		}
+		score += severityWeights[msg.pri] * weight
+	}
+	return
+}
+
+// sortByScore orders the domains from the highest risk score, keeping domains
+// with the same score in the order they were checked.
+func sortByScore(domains []DomainNS) {
+	sort.SliceStable(domains, func(i, j int) bool {
+		return domains[i].Score > domains[j].Score
+	})
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"log"
+	"net"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// Referrals followed from the root before giving up looking up a zone's name
+// servers, more than any real delegation chain needs
+const maxReferrals = 10
+
+// namedRoot is the root name servers' addresses, see named.root.
+//
+//go:embed named.root
+var namedRoot string
+
+// fallbackResolvers replace the system's recursive resolvers with
+// --no-system-resolver, or when the system has none configured.
+var fallbackResolvers = []string{"1.1.1.1", "8.8.8.8", "9.9.9.9", "2606:4700:4700::1111", "2001:4860:4860::8888"}
+
+// rootServers are the addresses, with port, of the root name servers in the
+// embedded hints, IPv4 first.
+var rootServers = parseRootHints(namedRoot)
+
+// parseRootHints returns the addresses of the root name servers in the hints,
+// IPv4 first so hosts without IPv6 connectivity don't wait on each timeout.
+func parseRootHints(hints string) (servers []string) {
+	var v6 []string
+
+	zp := dns.NewZoneParser(strings.NewReader(hints), ".", "named.root")
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		switch rr := rr.(type) {
+		case *dns.A:
+			servers = append(servers, net.JoinHostPort(rr.A.String(), "53"))
+		case *dns.AAAA:
+			v6 = append(v6, net.JoinHostPort(rr.AAAA.String(), "53"))
+		}
+	}
+	if err := zp.Err(); err != nil {
+		log.Fatalln("Invalid embedded root hints:", err)
+	}
+
+	return append(servers, v6...)
+}
+
+// systemResolverMissing returns whether the host has no resolv.conf, such as
+// a scratch container, where Go's resolver would only try localhost. Windows
+// doesn't use resolv.conf.
+func systemResolverMissing() bool {
+	if runtime.GOOS == "windows" {
+		return false
+	}
+	_, err := os.Stat("/etc/resolv.conf")
+	return os.IsNotExist(err)
+}
+
+// useFallbackResolvers sends all future lookups, that would otherwise go via
+// the system's resolvers, to the fallbackResolvers in turn, and finds parent
+// zones' name servers from the root hints.
+func useFallbackResolvers() {
+	noSystemResolver = true
+	resolver = &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (conn net.Conn, err error) {
+			if proxyDialer != nil {
+				// The resolver uses TCP when Dial doesn't return a net.PacketConn
+				network = "tcp"
+			}
+			for _, r := range fallbackResolvers {
+				conn, err = dialTCP(ctx, network, net.JoinHostPort(r, "53"))
+				if err == nil {
+					return conn, nil
+				}
+			}
+			return nil, err
+		},
+	}
+}
+
+// lookupNS returns the names of the zone's name servers. Without the system's
+// resolvers, they're found by following referrals from the root name servers.
+func lookupNS(ctx context.Context, zone string) ([]string, error) {
+	if noSystemResolver {
+		return lookupNSFromRoot(ctx, zone)
+	}
+
+	nss, err := resolver.LookupNS(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+	hosts := make([]string, len(nss))
+	for i, ns := range nss {
+		hosts[i] = ns.Host
+	}
+	return hosts, nil
+}
+
+// lookupNSFromRoot queries the root name servers for the zone's NS records,
+// following referrals until a server answers or delegates the zone itself.
+// Referrals without glue are followed by looking up the servers' addresses
+// via resolver.
+func lookupNSFromRoot(ctx context.Context, zone string) ([]string, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(zone), dns.TypeNS)
+	m.RecursionDesired = false
+
+	servers := rootServers
+	for i := 0; i < maxReferrals; i++ {
+		var (
+			r   *dns.Msg
+			err error
+		)
+		for _, server := range servers {
+			if r, err = exchangeContext(ctx, m, server); err == nil {
+				break
+			}
+			if ctx.Err() != nil {
+				return nil, err
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch r.Rcode {
+		case dns.RcodeSuccess:
+		case dns.RcodeNameError:
+			return nil, newDomainError(ErrNXDomain, "Zone %s not found", zone)
+		default:
+			return nil, newDomainError(rcodeClass(r.Rcode), "Looking up NS for zone %s returned %s", zone, dns.RcodeToString[r.Rcode])
+		}
+
+		var hosts []string
+		for _, a := range r.Answer {
+			if ns, ok := a.(*dns.NS); ok && strings.EqualFold(ns.Hdr.Name, m.Question[0].Name) {
+				hosts = append(hosts, ns.Ns)
+			}
+		}
+		if len(hosts) > 0 {
+			return hosts, nil
+		}
+
+		// A referral, either the zone's own delegation or one closer to it
+		referral := make(map[string]bool)
+		for _, a := range r.Ns {
+			ns, ok := a.(*dns.NS)
+			if !ok {
+				continue
+			}
+			if strings.EqualFold(ns.Hdr.Name, m.Question[0].Name) {
+				hosts = append(hosts, ns.Ns)
+			}
+			referral[strings.ToLower(ns.Ns)] = true
+		}
+		if len(hosts) > 0 {
+			return hosts, nil
+		}
+		if len(referral) == 0 {
+			return nil, newDomainError(ErrBadDelegation, "Could not find NS for zone %s", zone)
+		}
+
+		servers = glue(r.Extra, referral)
+		if len(servers) == 0 {
+			for ns := range referral {
+				addrs, err := resolver.LookupHost(ctx, ns)
+				if err != nil {
+					continue
+				}
+				for _, addr := range addrs {
+					servers = append(servers, net.JoinHostPort(addr, "53"))
+				}
+			}
+		}
+		if len(servers) == 0 {
+			return nil, newDomainError(ErrBadDelegation, "Could not find addresses of the name servers referred to for zone %s", zone)
+		}
+	}
+
+	return nil, newDomainError(ErrBadDelegation, "Too many referrals looking up NS for zone %s", zone)
+}
+
+// glue returns the addresses, with port, of the referral's name servers from
+// a response's additional section, IPv4 first.
+func glue(extra []dns.RR, referral map[string]bool) (servers []string) {
+	var v6 []string
+	for _, rr := range extra {
+		if !referral[strings.ToLower(rr.Header().Name)] {
+			continue
+		}
+		switch rr := rr.(type) {
+		case *dns.A:
+			servers = append(servers, net.JoinHostPort(rr.A.String(), "53"))
+		case *dns.AAAA:
+			v6 = append(v6, net.JoinHostPort(rr.AAAA.String(), "53"))
+		}
+	}
+	return append(servers, v6...)
+}
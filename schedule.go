@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/deckarep/golang-set"
+	"github.com/robfig/cron"
+)
+
+// schedule is a cron expression and where the results of the audits it runs
+// are written, emailed and uploaded.
+type schedule struct {
+	spec    string
+	cron    cron.Schedule
+	outputs auditOutputs
+}
+
+// parseSchedule parses a five field cron expression, optionally followed by
+// settings overriding the flags for its audits: upload=, output= and mail-to=,
+// the last two can be given more than once. A bare sixth field is an upload
+// destination, eg "0 6 * * * s3://bucket/daily/ mail-to=ops@example.com".
+func parseSchedule(s string) (schedule, error) {
+	fields := strings.Fields(s)
+	if len(fields) < 5 {
+		return schedule{}, errors.New(fmt.Sprintf("Expected a five field cron expression and optional settings, got %q", s))
+	}
+
+	spec := strings.Join(fields[:5], " ")
+	sched, err := cron.ParseStandard(spec)
+	if err != nil {
+		return schedule{}, err
+	}
+
+	outputs := flagOutputs()
+	var specs, mailTo []string
+	for i, field := range fields[5:] {
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 {
+			if i > 0 {
+				return schedule{}, errors.New(fmt.Sprintf("Invalid setting %s, expected upload=, output= or mail-to=", field))
+			}
+			parts = []string{"upload", field}
+		}
+
+		switch parts[0] {
+		case "upload":
+			outputs.upload = parts[1]
+		case "output":
+			if _, _, err := parseOutput(parts[1]); err != nil {
+				return schedule{}, err
+			}
+			specs = append(specs, parts[1])
+		case "mail-to":
+			mailTo = append(mailTo, parts[1])
+		default:
+			return schedule{}, errors.New(fmt.Sprintf("Unknown setting %s, expected upload, output or mail-to", parts[0]))
+		}
+	}
+	if specs != nil {
+		outputs.specs = specs
+	}
+	if mailTo != nil {
+		outputs.mailTo = mailTo
+	}
+
+	return schedule{spec: spec, cron: sched, outputs: outputs}, nil
+}
+
+// runSchedules runs an audit each time a schedule is due, forever. Audits run
+// one at a time, if one overruns, schedules that came due during it run once
+// it finishes.
+func runSchedules(schedules []schedule, requiredNS, allowedAddrs mapset.Set) {
+
+	next := make([]time.Time, len(schedules))
+	for i, sched := range schedules {
+		next[i] = sched.cron.Next(time.Now())
+	}
+
+	for {
+		due := 0
+		for i := range schedules {
+			if next[i].Before(next[due]) {
+				due = i
+			}
+		}
+
+		log.Printf("Next audit at %s for schedule: %s", next[due].Format(time.RFC3339), schedules[due].spec)
+		time.Sleep(time.Until(next[due]))
+
+		domains, err := openDomains()
+		if err != nil {
+			log.Println("Error opening domains:", err)
+		} else {
+			if _, err := runAudit(domains, requiredNS, allowedAddrs, schedules[due].outputs); err != nil {
+				log.Println("Error running audit:", err)
+			}
+			domains.Close()
+		}
+
+		next[due] = schedules[due].cron.Next(time.Now())
+	}
+}
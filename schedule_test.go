@@ -0,0 +1,52 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSchedule(t *testing.T) {
+	flags := flagOutputs()
+
+	tests := []struct {
+		s       string
+		spec    string
+		outputs auditOutputs
+		wantErr bool
+	}{
+		{s: "0 6 * * *", spec: "0 6 * * *", outputs: flags},
+		{
+			s:       "0 6 * * 1-5 s3://bucket/daily/",
+			spec:    "0 6 * * 1-5",
+			outputs: auditOutputs{specs: flags.specs, upload: "s3://bucket/daily/", mailTo: flags.mailTo},
+		},
+		{
+			s:       "*/15 * * * * upload=gs://bucket/ output=json=out.json output=csv=out.csv mail-to=ops@example.com",
+			spec:    "*/15 * * * *",
+			outputs: auditOutputs{specs: []string{"json=out.json", "csv=out.csv"}, upload: "gs://bucket/", mailTo: []string{"ops@example.com"}},
+		},
+		{s: "0 6 * *", wantErr: true},
+		{s: "0 25 * * *", wantErr: true},
+		{s: "0 6 * * * s3://bucket/ s3://other/", wantErr: true},
+		{s: "0 6 * * * notify=ops@example.com", wantErr: true},
+		{s: "0 6 * * * output=json:out.json", wantErr: true},
+		{s: "0 6 * * * output=pdf=out.pdf", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		sched, err := parseSchedule(tt.s)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseSchedule(%q) error %v, want error %t", tt.s, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if sched.spec != tt.spec {
+			t.Errorf("parseSchedule(%q) spec %q, want %q", tt.s, sched.spec, tt.spec)
+		}
+		if !reflect.DeepEqual(sched.outputs, tt.outputs) {
+			t.Errorf("parseSchedule(%q) outputs %+v, want %+v", tt.s, sched.outputs, tt.outputs)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/miekg/dns"
+)
+
+// responseSize is the size of a UDP response from the zone's name server.
+type responseSize struct {
+	Type      string `json:"type"`
+	Size      int    `json:"size"`
+	Truncated bool   `json:"truncated"`
+}
+
+// queryResponseSizes queries the zone's name server for NS and DNSKEY records
+// over UDP, advertising a 4096 byte EDNS buffer so the server's own limits
+// decide whether the response is truncated. Truncated responses aren't retried
+// over TCP, as their size is what's checked. Queries via --proxy are over TCP,
+// so --check-size is turned off with it.
+func queryResponseSizes(ctx context.Context, domain, nameServer string) (sizes []responseSize, err error) {
+	for _, qtype := range []uint16{dns.TypeNS, dns.TypeDNSKEY} {
+		m := new(dns.Msg)
+		m.SetQuestion(domain, qtype)
+		m.SetEdns0(dns.DefaultMsgSize, true)
+
+		r, err := queryMsg(ctx, m, nameServer)
+		if err != nil {
+			return nil, err
+		}
+
+		sizes = append(sizes, responseSize{Type: dns.TypeToString[qtype], Size: r.Len(), Truncated: r.Truncated})
+	}
+	return
+}
+
+// compareResponseSizes reports responses larger than --max-udp-size that
+// weren't truncated, which may be fragmented and dropped on the way to
+// resolvers.
+func compareResponseSizes(domainNS *DomainNS) (errors int) {
+
+	for _, size := range domainNS.ResponseSizes {
+		if size.Size > *argsMaxUDP && !size.Truncated {
+			domainNS.MSGs = append(domainNS.MSGs, msg{pri: LOG_WARNING, check: checkSize, msg: fmt.Sprintf("%s response of %d bytes over UDP exceeds %d bytes without being truncated", size.Type, size.Size, *argsMaxUDP)})
+			errors++
+		}
+	}
+
+	return
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// soaTimers is the zone's SOA record, the timers are in seconds.
+type soaTimers struct {
+	Serial  uint32 `json:"serial"`
+	Refresh uint32 `json:"refresh"`
+	Retry   uint32 `json:"retry"`
+	Expire  uint32 `json:"expire"`
+	Minimum uint32 `json:"minimum"`
+}
+
+// soaRange is the allowed values of a timer, inclusive.
+type soaRange struct {
+	min, max uint32
+}
+
+// soaRanges defaults to RFC 1912's recommendations, and RFC 2308's for the
+// negative caching TTL in minimum, overridden with --soa-range.
+var soaRanges = map[string]soaRange{
+	"refresh": {1200, 43200},
+	"retry":   {180, 7200},
+	"expire":  {1209600, 2419200},
+	"minimum": {300, 86400},
+}
+
+// parseSOARanges overrides soaRanges with timer=min-max pairs, eg
+// expire=604800-2419200.
+func parseSOARanges(specs []string) error {
+	for _, spec := range specs {
+		parts := strings.SplitN(spec, "=", 2)
+		if len(parts) != 2 {
+			return errors.New(fmt.Sprintf("Invalid range %s, expected timer=min-max", spec))
+		}
+
+		if _, ok := soaRanges[parts[0]]; !ok {
+			return errors.New(fmt.Sprintf("Invalid timer %s, expected refresh, retry, expire or minimum", parts[0]))
+		}
+
+		bounds := strings.SplitN(parts[1], "-", 2)
+		if len(bounds) != 2 {
+			return errors.New(fmt.Sprintf("Invalid range %s, expected min-max", parts[1]))
+		}
+		min, err := strconv.ParseUint(bounds[0], 10, 32)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Invalid minimum %s", bounds[0]))
+		}
+		max, err := strconv.ParseUint(bounds[1], 10, 32)
+		if err != nil || max < min {
+			return errors.New(fmt.Sprintf("Invalid maximum %s", bounds[1]))
+		}
+
+		soaRanges[parts[0]] = soaRange{uint32(min), uint32(max)}
+	}
+	return nil
+}
+
+// querySOA fetches the zone's SOA record from the zone's name server.
+func querySOA(ctx context.Context, domain, nameServer string) (*soaTimers, error) {
+	r, err := query(ctx, domain, nameServer, dns.TypeSOA)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Rcode != dns.RcodeSuccess {
+		return nil, newDomainError(rcodeClass(r.Rcode), "Bad response for SOA records for domain:%s", domain)
+	}
+
+	for _, a := range r.Answer {
+		if soa, ok := a.(*dns.SOA); ok {
+			return &soaTimers{Serial: soa.Serial, Refresh: soa.Refresh, Retry: soa.Retry, Expire: soa.Expire, Minimum: soa.Minttl}, nil
+		}
+	}
+
+	return nil, newDomainError(ErrBadDelegation, "No SOA record for domain:%s", domain)
+}
+
+func compareSOA(domainNS *DomainNS) (errors int) {
+
+	soa := domainNS.SOA
+	if soa == nil {
+		return
+	}
+	for _, timer := range []struct {
+		name  string
+		value uint32
+	}{{"refresh", soa.Refresh}, {"retry", soa.Retry}, {"expire", soa.Expire}, {"minimum", soa.Minimum}} {
+		r := soaRanges[timer.name]
+		if timer.value < r.min || timer.value > r.max {
+			domainNS.MSGs = append(domainNS.MSGs, msg{pri: LOG_WARNING, check: checkSOA, msg: fmt.Sprintf("SOA %s %d outside %d-%d", timer.name, timer.value, r.min, r.max)})
+			errors++
+		}
+	}
+
+	// Secondaries retrying no quicker than they refresh, or expiring the zone
+	// before they've had a chance to retry, are misconfigured regardless of
+	// the ranges
+	if soa.Retry >= soa.Refresh {
+		domainNS.MSGs = append(domainNS.MSGs, msg{pri: LOG_WARNING, check: checkSOA, msg: fmt.Sprintf("SOA retry %d not less than refresh %d", soa.Retry, soa.Refresh)})
+		errors++
+	}
+	if soa.Expire <= soa.Refresh+soa.Retry {
+		domainNS.MSGs = append(domainNS.MSGs, msg{pri: LOG_WARNING, check: checkSOA, msg: fmt.Sprintf("SOA expire %d not greater than refresh plus retry %d", soa.Expire, soa.Refresh+soa.Retry)})
+		errors++
+	}
+
+	return
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSOARanges(t *testing.T) {
+	defaults := map[string]soaRange{
+		"refresh": {1200, 43200},
+		"retry":   {180, 7200},
+		"expire":  {1209600, 2419200},
+		"minimum": {300, 86400},
+	}
+
+	tests := []struct {
+		specs   []string
+		want    map[string]soaRange
+		wantErr bool
+	}{
+		{specs: nil, want: defaults},
+		{
+			specs: []string{"expire=604800-2419200", "minimum=60-60"},
+			want: map[string]soaRange{
+				"refresh": {1200, 43200},
+				"retry":   {180, 7200},
+				"expire":  {604800, 2419200},
+				"minimum": {60, 60},
+			},
+		},
+		{specs: []string{"expire"}, wantErr: true},
+		{specs: []string{"serial=1-2"}, wantErr: true},
+		{specs: []string{"retry=180"}, wantErr: true},
+		{specs: []string{"retry=x-7200"}, wantErr: true},
+		{specs: []string{"retry=7200-180"}, wantErr: true},
+		{specs: []string{"retry=0-4294967296"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		soaRanges = make(map[string]soaRange)
+		for timer, r := range defaults {
+			soaRanges[timer] = r
+		}
+
+		err := parseSOARanges(tt.specs)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseSOARanges(%q) error %v, want error %t", tt.specs, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(soaRanges, tt.want) {
+			t.Errorf("parseSOARanges(%q) got %v, want %v", tt.specs, soaRanges, tt.want)
+		}
+	}
+
+	soaRanges = defaults
+}
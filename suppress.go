@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// suppression accepts a domain's failures from a check, or all checks if check
+// is *, until it expires.
+type suppression struct {
+	domain  string
+	check   string
+	expires time.Time // zero if it never expires
+	reason  string
+}
+
+var suppressions []suppression
+
+// loadSuppressions reads the suppression file, a CSV file of domain, check,
+// expiry date (YYYY-MM-DD, or empty to never expire) and reason. Lines
+// starting with # are ignored.
+func loadSuppressions(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.FieldsPerRecord = 4
+	r.TrimLeadingSpace = true
+
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		s := suppression{
+			domain: normaliseDomain(record[0]),
+			check:  record[1],
+			reason: record[3],
+		}
+
+		if s.check != "*" {
+			if !validCheck(s.check) {
+				return errors.New(fmt.Sprintf("Unknown check %s for domain %s, expected * or one of: %s", s.check, record[0], strings.Join(checks, ", ")))
+			}
+		}
+
+		if record[2] != "" {
+			if s.expires, err = time.Parse("2006-01-02", record[2]); err != nil {
+				return errors.New(fmt.Sprintf("Invalid expiry date %s for domain %s, expected YYYY-MM-DD", record[2], record[0]))
+			}
+			// Suppressions last until the end of their expiry date
+			s.expires = s.expires.Add(24 * time.Hour)
+			if time.Now().After(s.expires) {
+				log.Printf("Suppression of %s for domain %s expired on %s", s.check, record[0], record[2])
+			}
+		}
+
+		suppressions = append(suppressions, s)
+	}
+
+	return nil
+}
+
+// normaliseDomain returns the domain in lower case without a trailing dot.
+func normaliseDomain(domain string) string {
+	return strings.ToLower(strings.TrimRight(strings.TrimSpace(domain), "."))
+}
+
+// markSuppressed downgrades messages matching an unexpired suppression to
+// LOG_INFO, noting the reason, returning how many were downgraded.
+func markSuppressed(domainNS *DomainNS) (n int) {
+	domain := normaliseDomain(domainNS.Domain)
+	now := time.Now()
+
+	for i := range domainNS.MSGs {
+		if domainNS.MSGs[i].pri == LOG_INFO {
+			continue
+		}
+		for _, s := range suppressions {
+			if s.domain != domain || (s.check != "*" && s.check != domainNS.MSGs[i].check) {
+				continue
+			}
+			if !s.expires.IsZero() && now.After(s.expires) {
+				continue
+			}
+			domainNS.MSGs[i].pri = LOG_INFO
+			domainNS.MSGs[i].suppressed = s.reason
+			n++
+			break
+		}
+	}
+	return
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/deckarep/golang-set"
+	"github.com/miekg/dns"
+)
+
+// zoneTransfer is the result of transferring the zone from one of its servers,
+// with the records differing from the reference server's copy.
+type zoneTransfer struct {
+	Server string `json:"server"`
+	// Primary is set for the server named in the SOA MNAME
+	Primary bool   `json:"primary,omitempty"`
+	Serial  uint32 `json:"serial,omitempty"`
+	Records int    `json:"records,omitempty"`
+	// Missing and Extra are the records missing from and only in this
+	// server's copy of the zone, compared to the reference's
+	Missing []string `json:"missing,omitempty"`
+	Extra   []string `json:"extra,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// transferZones transfers the zone by AXFR from its primary, named in the SOA
+// MNAME, and each of its name servers, comparing each copy to the primary's, or
+// the first server's if the primary's couldn't be transferred.
+func transferZones(ctx context.Context, domainNS *DomainNS, zoneNS string) (transfers []zoneTransfer, err error) {
+
+	r, err := query(ctx, domainNS.Domain, zoneNS, dns.TypeSOA)
+	if err != nil {
+		return nil, err
+	}
+
+	var primary string
+	for _, a := range r.Answer {
+		if soa, ok := a.(*dns.SOA); ok {
+			primary = strings.ToLower(soa.Ns)
+		}
+	}
+
+	servers := []string{}
+	if primary != "" {
+		servers = append(servers, primary)
+	}
+	for _, ns := range sortedSet(domainNS.ZoneNS) {
+		if strings.ToLower(ns) != primary {
+			servers = append(servers, ns)
+		}
+	}
+
+	reference := -1
+	zones := make([]mapset.Set, len(servers))
+	for i, server := range servers {
+		t := zoneTransfer{Server: server, Primary: server == primary}
+
+		records, serial, err := transferZone(ctx, domainNS.Domain, server)
+		if err != nil {
+			t.Error = err.Error()
+		} else {
+			t.Serial, t.Records = serial, records.Cardinality()
+			zones[i] = records
+			if reference < 0 {
+				reference = i
+			}
+		}
+
+		transfers = append(transfers, t)
+	}
+
+	for i := range transfers {
+		if zones[i] == nil || i == reference {
+			continue
+		}
+		transfers[i].Missing = sortedSet(zones[reference].Difference(zones[i]))
+		transfers[i].Extra = sortedSet(zones[i].Difference(zones[reference]))
+	}
+
+	return transfers, nil
+}
+
+// transferZone fetches the zone from the server by AXFR, signed with the
+// server's TSIG key, see transferKey, returning its records in presentation format and serial.
+func transferZone(ctx context.Context, domain, server string) (records mapset.Set, serial uint32, err error) {
+	address := server + ":53"
+	if mockAddr != "" {
+		address = mockAddr
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, settingsFrom(ctx).timeout)
+	defer cancel()
+
+	conn, err := dialTCP(ctx, "tcp", address)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	m := new(dns.Msg)
+	m.SetAxfr(domain)
+
+	t := &dns.Transfer{Conn: &dns.Conn{Conn: conn}}
+	if key := transferKey(server); key != nil {
+		t.TsigSecret = key.secrets()
+		m.SetTsig(key.Name, key.Algorithm, tsigFudge, time.Now().Unix())
+	}
+
+	env, err := t.In(m, address)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	records = mapset.NewSet()
+	for e := range env {
+		if e.Error != nil {
+			return nil, 0, e.Error
+		}
+		for _, rr := range e.RR {
+			// The serial is compared separately, so an out of date copy
+			// isn't also reported as a missing and extra SOA record
+			if soa, ok := rr.(*dns.SOA); ok {
+				serial = soa.Serial
+				continue
+			}
+			rr.Header().Name = strings.ToLower(rr.Header().Name)
+			records.Add(rr.String())
+		}
+	}
+
+	if records.Cardinality() == 0 {
+		return nil, 0, errors.New("Transfer returned no records")
+	}
+
+	return records, serial, nil
+}
+
+// compareTransfers reports servers the zone couldn't be transferred from and
+// copies of the zone differing from the reference.
+func compareTransfers(domainNS *DomainNS) (errors int) {
+
+	var reference *zoneTransfer
+	for i, t := range domainNS.Transfers {
+		if t.Error == "" {
+			reference = &domainNS.Transfers[i]
+			break
+		}
+	}
+
+	for _, t := range domainNS.Transfers {
+		if t.Error != "" {
+			domainNS.MSGs = append(domainNS.MSGs, msg{pri: LOG_ERR, check: checkTransfer, msg: fmt.Sprintf("Zone transfer from %s failed: %s", t.Server, t.Error)})
+			errors++
+			continue
+		}
+		if reference == nil || t.Server == reference.Server {
+			continue
+		}
+
+		if t.Serial != reference.Serial {
+			domainNS.MSGs = append(domainNS.MSGs, msg{pri: LOG_ERR, check: checkTransfer, msg: fmt.Sprintf("Zone serial %d on %s differs from %d on %s", t.Serial, t.Server, reference.Serial, reference.Server)})
+			errors++
+		}
+
+		if len(t.Missing) > 0 || len(t.Extra) > 0 {
+			domainNS.MSGs = append(domainNS.MSGs, msg{pri: LOG_ERR, check: checkTransfer, msg: fmt.Sprintf("Zone on %s differs from %s, missing %d records%s, extra %d records%s", t.Server, reference.Server, len(t.Missing), examples(t.Missing), len(t.Extra), examples(t.Extra))})
+			errors++
+		}
+	}
+
+	return
+}
+
+// examples returns up to the first three records, for messages.
+func examples(records []string) string {
+	if len(records) == 0 {
+		return ""
+	}
+
+	if len(records) > 3 {
+		return " (" + strings.Replace(strings.Join(records[:3], "; "), "\t", " ", -1) + ", ...)"
+	}
+	return " (" + strings.Replace(strings.Join(records, "; "), "\t", " ", -1) + ")"
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Seconds of clock skew allowed between nsaudit and servers verifying TSIG
+// signatures, the RFC 8945 recommendation
+const tsigFudge = 300
+
+// tsigKey is a TSIG key, see --tsig-key.
+type tsigKey struct {
+	Name      string
+	Algorithm string
+	Secret    string
+}
+
+// secrets returns the key in the form used by the dns package to verify
+// signed responses.
+func (k *tsigKey) secrets() map[string]string {
+	return map[string]string{k.Name: k.Secret}
+}
+
+var (
+	// tsigKeys in the order given, the first is used for zone transfers
+	// from servers without a key of their own.
+	tsigKeys []tsigKey
+
+	// serverKeys are the keys queries to each server, by name or address, are
+	// signed with, see --tsig-server.
+	serverKeys = make(map[string]*tsigKey)
+)
+
+// tsigAlgorithms are the algorithms accepted by --tsig-key.
+var tsigAlgorithms = map[string]string{
+	"hmac-sha1":   dns.HmacSHA1,
+	"hmac-sha256": dns.HmacSHA256,
+	"hmac-sha512": dns.HmacSHA512,
+}
+
+// parseTSIGKeys parses each key as [algorithm:]name:secret, the same as dig -y,
+// with algorithm defaulting to hmac-sha256.
+func parseTSIGKeys(keys []string) error {
+	for _, k := range keys {
+		parts := strings.Split(k, ":")
+		if len(parts) == 2 {
+			parts = append([]string{"hmac-sha256"}, parts...)
+		}
+		if len(parts) != 3 || parts[1] == "" || parts[2] == "" {
+			return errors.New(fmt.Sprintf("Invalid key %s, expected [algorithm:]name:secret", k))
+		}
+
+		algorithm, ok := tsigAlgorithms[strings.ToLower(parts[0])]
+		if !ok {
+			return errors.New(fmt.Sprintf("Unsupported algorithm %s, expected hmac-sha1, hmac-sha256 or hmac-sha512", parts[0]))
+		}
+
+		tsigKeys = append(tsigKeys, tsigKey{Name: dns.Fqdn(strings.ToLower(parts[1])), Algorithm: algorithm, Secret: parts[2]})
+	}
+	return nil
+}
+
+// parseServerKeys parses each server=key, naming the --tsig-key to sign
+// queries to the server with.
+func parseServerKeys(servers []string) error {
+	for _, s := range servers {
+		parts := strings.SplitN(s, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return errors.New(fmt.Sprintf("Invalid server key %s, expected server=key", s))
+		}
+
+		name := dns.Fqdn(strings.ToLower(parts[1]))
+		var key *tsigKey
+		for i := range tsigKeys {
+			if tsigKeys[i].Name == name {
+				key = &tsigKeys[i]
+			}
+		}
+		if key == nil {
+			return errors.New(fmt.Sprintf("Unknown key %s for server %s, add it with --tsig-key", parts[1], parts[0]))
+		}
+
+		serverKeys[normaliseDomain(parts[0])] = key
+	}
+	return nil
+}
+
+// serverKey returns the key to sign queries to the server with, a name or
+// address with or without a port, or nil if they aren't signed.
+func serverKey(server string) *tsigKey {
+	if host, _, err := net.SplitHostPort(server); err == nil {
+		server = host
+	}
+	return serverKeys[normaliseDomain(server)]
+}
+
+// transferKey returns the key to sign zone transfers from the server with, its
+// own key or the first --tsig-key.
+func transferKey(server string) *tsigKey {
+	if key := serverKey(server); key != nil {
+		return key
+	}
+	if len(tsigKeys) > 0 {
+		return &tsigKeys[0]
+	}
+	return nil
+}
+
+// signMsg returns a copy of the query signed with the key, leaving m unsigned
+// so it can be resent, and signed again, on retries.
+func signMsg(m *dns.Msg, key *tsigKey) *dns.Msg {
+	m = m.Copy()
+	m.SetTsig(key.Name, key.Algorithm, tsigFudge, time.Now().Unix())
+	return m
+}
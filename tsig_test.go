@@ -0,0 +1,47 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestParseTSIGKeys(t *testing.T) {
+	tests := []struct {
+		keys    []string
+		want    []tsigKey
+		wantErr bool
+	}{
+		{
+			keys: []string{"Transfer.Key:c2VjcmV0"},
+			want: []tsigKey{{Name: "transfer.key.", Algorithm: dns.HmacSHA256, Secret: "c2VjcmV0"}},
+		},
+		{
+			keys: []string{"HMAC-SHA512:a.key.:c2VjcmV0", "hmac-sha1:b.key:b3RoZXI="},
+			want: []tsigKey{
+				{Name: "a.key.", Algorithm: dns.HmacSHA512, Secret: "c2VjcmV0"},
+				{Name: "b.key.", Algorithm: dns.HmacSHA1, Secret: "b3RoZXI="},
+			},
+		},
+		{keys: []string{"c2VjcmV0"}, wantErr: true},
+		{keys: []string{"key:"}, wantErr: true},
+		{keys: []string{":c2VjcmV0"}, wantErr: true},
+		{keys: []string{"hmac-md5:key:c2VjcmV0"}, wantErr: true},
+		{keys: []string{"a:b:c:d"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tsigKeys = nil
+
+		err := parseTSIGKeys(tt.keys)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseTSIGKeys(%q) error %v, want error %t", tt.keys, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(tsigKeys, tt.want) {
+			t.Errorf("parseTSIGKeys(%q) got %+v, want %+v", tt.keys, tsigKeys, tt.want)
+		}
+	}
+	tsigKeys = nil
+}
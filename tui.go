@@ -0,0 +1,349 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/deckarep/golang-set"
+	"golang.org/x/term"
+)
+
+// Row statuses, in order of severity
+const (
+	tuiPending = iota
+	tuiOK
+	tuiWarn
+	tuiErr
+	tuiCrit
+)
+
+var tuiStatusNames = []string{"....", "OK", "WARN", "ERR", "CRIT"}
+var tuiStatusColours = []string{"\x1b[90m", "\x1b[32m", "\x1b[33m", "\x1b[31m", "\x1b[1;31m"}
+
+// Status filters, cycled through with f
+const (
+	tuiFilterAll = iota
+	tuiFilterFailing
+	tuiFilterOK
+	tuiFilterPending
+)
+
+var tuiFilterNames = []string{"all", "failing", "ok", "pending"}
+
+type tuiRow struct {
+	name     string
+	status   int
+	domainNS DomainNS
+}
+
+type tuiResult struct {
+	row      int
+	domainNS DomainNS
+}
+
+type tui struct {
+	rows     []tuiRow
+	filter   int
+	selected int // index into rows, -1 if nothing is visible
+	offset   int // first visible line of the table
+}
+
+// runTUI checks the domains read from r, showing results in a table as they
+// complete until the user quits.
+func runTUI(r io.Reader, requiredNS, allowedAddrs mapset.Set) error {
+
+	var names []string
+	err := readDomains(r, func(domain string) {
+		names = append(names, domain)
+	})
+	if err != nil {
+		return err
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return errors.New("--tui requires a terminal")
+	}
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(fd, state)
+
+	// Log lines would draw over the UI
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	// Switch to the alternate screen and hide the cursor
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	defer fmt.Print("\x1b[?25h\x1b[?1049l")
+
+	// Workers live for the whole session so domains can be re-checked
+	jobs := make(chan int, len(names))
+	results := make(chan tuiResult)
+	for i := 0; i < *argsW; i++ {
+		go func() {
+			for row := range jobs {
+				domainNS, _ := checkDomain(names[row], flagOptions())
+				compareNS(requiredNS, allowedAddrs, &domainNS)
+				results <- tuiResult{row: row, domainNS: domainNS}
+			}
+		}()
+	}
+
+	t := &tui{rows: make([]tuiRow, len(names))}
+	for i, name := range names {
+		t.rows[i].name = name
+		jobs <- i
+	}
+	t.move(0)
+
+	keys := make(chan string)
+	go readKeys(os.Stdin, keys)
+
+	for {
+		t.draw()
+
+		select {
+		case result := <-results:
+			t.rows[result.row].domainNS = result.domainNS
+			t.rows[result.row].status = tuiStatus(&result.domainNS)
+		case key := <-keys:
+			switch key {
+			case "q", "\x03":
+				return nil
+			case "up", "k":
+				t.move(-1)
+			case "down", "j":
+				t.move(1)
+			case "f":
+				t.filter = (t.filter + 1) % len(tuiFilterNames)
+				t.move(0)
+			case "r":
+				if t.selected < 0 || t.rows[t.selected].status == tuiPending {
+					break
+				}
+				t.rows[t.selected].status = tuiPending
+				go func(row int) { jobs <- row }(t.selected)
+			}
+		}
+	}
+}
+
+// readKeys sends each key pressed, arrow keys are sent as "up" and "down".
+func readKeys(r io.Reader, keys chan<- string) {
+	buf := make([]byte, 16)
+	for {
+		n, err := r.Read(buf)
+		if err != nil {
+			return
+		}
+
+		if n >= 3 && buf[0] == 0x1b && buf[1] == '[' {
+			switch buf[2] {
+			case 'A':
+				keys <- "up"
+			case 'B':
+				keys <- "down"
+			}
+			continue
+		}
+
+		for _, b := range buf[:n] {
+			keys <- string(b)
+		}
+	}
+}
+
+// tuiStatus returns the status of the most severe message shown.
+func tuiStatus(domainNS *DomainNS) int {
+	status := tuiOK
+	for _, msg := range domainNS.MSGs {
+		switch {
+		case msg.pri == LOG_CRIT:
+			status = tuiCrit
+		case msg.pri == LOG_ERR && status < tuiErr:
+			status = tuiErr
+		case msg.pri == LOG_WARNING && *argsZ && status < tuiWarn:
+			status = tuiWarn
+		}
+	}
+	return status
+}
+
+// visible returns the indexes of rows matching the filter.
+func (t *tui) visible() (rows []int) {
+	for i, row := range t.rows {
+		var match bool
+		switch t.filter {
+		case tuiFilterAll:
+			match = true
+		case tuiFilterFailing:
+			match = row.status >= tuiWarn
+		case tuiFilterOK:
+			match = row.status == tuiOK
+		case tuiFilterPending:
+			match = row.status == tuiPending
+		}
+		if match {
+			rows = append(rows, i)
+		}
+	}
+	return
+}
+
+// move selects the visible row delta rows from the currently selected row.
+func (t *tui) move(delta int) {
+	visible := t.visible()
+	if len(visible) == 0 {
+		t.selected = -1
+		return
+	}
+
+	pos := 0
+	for i, row := range visible {
+		if row == t.selected {
+			pos = i
+		}
+	}
+
+	pos += delta
+	if pos < 0 {
+		pos = 0
+	}
+	if pos >= len(visible) {
+		pos = len(visible) - 1
+	}
+	t.selected = visible[pos]
+}
+
+func (t *tui) draw() {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		width, height = 80, 24
+	}
+
+	detailHeight := height / 3
+	if detailHeight > 10 {
+		detailHeight = 10
+	}
+	tableHeight := height - detailHeight - 3
+	if tableHeight < 1 {
+		tableHeight = 1
+	}
+
+	var buf bytes.Buffer
+	line := func(format string, a ...interface{}) {
+		buf.WriteString(truncate(fmt.Sprintf(format, a...), width))
+		buf.WriteString("\x1b[K\r\n")
+	}
+
+	checked, failing := 0, 0
+	for _, row := range t.rows {
+		if row.status != tuiPending {
+			checked++
+		}
+		if row.status >= tuiWarn {
+			failing++
+		}
+	}
+
+	buf.WriteString("\x1b[H")
+	line("nsaudit: %d/%d checked, %d failing, filter: %s", checked, len(t.rows), failing, tuiFilterNames[t.filter])
+
+	visible := t.visible()
+	for i, row := range visible {
+		if row == t.selected {
+			if i < t.offset {
+				t.offset = i
+			}
+			if i >= t.offset+tableHeight {
+				t.offset = i - tableHeight + 1
+			}
+		}
+	}
+	if t.offset > len(visible) {
+		t.offset = 0
+	}
+
+	for i := t.offset; i < t.offset+tableHeight; i++ {
+		if i >= len(visible) {
+			line("")
+			continue
+		}
+		row := t.rows[visible[i]]
+		selected := ""
+		if visible[i] == t.selected {
+			selected = "\x1b[7m"
+		}
+		// Colour codes don't take up any width, so they're not counted when truncating
+		buf.WriteString(fmt.Sprintf("%s%s%-4s\x1b[0m%s ", selected, tuiStatusColours[row.status], tuiStatusNames[row.status], selected))
+		buf.WriteString(truncate(row.name, width-5))
+		buf.WriteString("\x1b[0m\x1b[K\r\n")
+	}
+
+	var details []string
+	if t.selected >= 0 {
+		row := t.rows[t.selected]
+		details = append(details, fmt.Sprintf("--- %s ---", row.name))
+		if row.status != tuiPending {
+			domainNS := row.domainNS
+			if domainNS.RegistrarNS != nil {
+				details = append(details, fmt.Sprintf("Registrar NS: %v", domainNS.RegistrarNS))
+			}
+			if domainNS.ZoneNS != nil {
+				details = append(details, fmt.Sprintf("Zone NS: %v", domainNS.ZoneNS))
+			}
+			for _, msg := range domainNS.MSGs {
+				switch msg.pri {
+				case LOG_CRIT:
+					details = append(details, "CRIT: "+msg.msg)
+				case LOG_ERR:
+					details = append(details, "ERR: "+msg.msg)
+				case LOG_WARNING:
+					if *argsZ {
+						details = append(details, "WARN: "+msg.msg)
+					}
+				case LOG_INFO:
+					if msg.suppressed != "" {
+						details = append(details, fmt.Sprintf("SUPPRESSED: %s (%s)", msg.msg, msg.suppressed))
+					} else {
+						details = append(details, "INFO: "+msg.msg)
+					}
+				default:
+					details = append(details, "UNKN: "+msg.msg)
+				}
+			}
+		}
+	}
+	for i := 0; i < detailHeight; i++ {
+		if i < len(details) {
+			line("%s", details[i])
+		} else {
+			line("")
+		}
+	}
+
+	buf.WriteString(truncate("up/down select, f filter, r re-check, q quit", width))
+	buf.WriteString("\x1b[K\x1b[J")
+
+	os.Stdout.Write(buf.Bytes())
+}
+
+// truncate shortens s to at most width characters.
+func truncate(s string, width int) string {
+	if width < 0 {
+		width = 0
+	}
+	r := []rune(s)
+	if len(r) > width {
+		return string(r[:width])
+	}
+	return s
+}
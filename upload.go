@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"path"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// uploadReport is a report uploaded with --upload, written to body by its
+// writer during the run.
+type uploadReport struct {
+	ext         string
+	contentType string
+	body        *bytes.Buffer
+}
+
+// newUploadReports returns the JSON and HTML reports to upload and the writers
+// producing them, which are added to the run's outputs.
+func newUploadReports() ([]uploadReport, []outputWriter) {
+	reports := []uploadReport{
+		{ext: "json", contentType: "application/json", body: new(bytes.Buffer)},
+		{ext: "html", contentType: "text/html", body: new(bytes.Buffer)},
+	}
+	return reports, []outputWriter{
+		&jsonWriter{w: nopCloser{reports[0].body}},
+		&htmlWriter{jsonWriter{w: nopCloser{reports[1].body}}},
+	}
+}
+
+// upload stores body as name under dest, either s3://bucket/prefix/ or
+// gs://bucket/prefix/. Credentials are found the same way as the AWS and
+// Google Cloud CLIs find them.
+func upload(dest, name, contentType string, body []byte) error {
+	u, err := url.Parse(dest)
+	if err != nil {
+		return err
+	}
+
+	bucket := u.Host
+	key := path.Join(strings.TrimPrefix(u.Path, "/"), name)
+
+	switch u.Scheme {
+	case "s3":
+		return uploadS3(bucket, key, contentType, body)
+	case "gs":
+		return uploadGCS(bucket, key, contentType, body)
+	}
+
+	return errors.New(fmt.Sprintf("Unsupported upload destination %s, must be s3:// or gs://", dest))
+}
+
+func uploadS3(bucket, key, contentType string, body []byte) error {
+	sess, err := session.NewSessionWithOptions(session.Options{SharedConfigState: session.SharedConfigEnable})
+	if err != nil {
+		return err
+	}
+
+	out, err := s3manager.NewUploader(sess).Upload(&s3manager.UploadInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Println("Uploaded report to:", out.Location)
+	return nil
+}
+
+func uploadGCS(bucket, key, contentType string, body []byte) error {
+	ctx := context.Background()
+
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	w := client.Bucket(bucket).Object(key).NewWriter(ctx)
+	w.ContentType = contentType
+	if _, err := w.Write(body); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	log.Printf("Uploaded report to: gs://%s/%s", bucket, key)
+	return nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/deckarep/golang-set"
+	"golang.org/x/term"
+)
+
+// nsSnapshot is a domain's NS records as of the last round.
+type nsSnapshot struct {
+	RegistrarNS mapset.Set
+	ZoneNS      mapset.Set
+}
+
+// runWatch re-checks the domains file every interval, printing the name
+// servers added to or removed from each domain's registrar and zone NS records.
+func runWatch(interval time.Duration) {
+
+	colour := term.IsTerminal(int(os.Stdout.Fd()))
+	previous := make(map[string]nsSnapshot)
+
+	for {
+		resetHostCaches()
+
+		domains, err := openDomains()
+		if err != nil {
+			log.Println("Error opening domains:", err)
+		} else {
+			for domainNS := range checkDomains(domains) {
+				if domainNS.Error != nil {
+					log.Printf("Error checking domain %s: %s", domainNS.Domain, domainNS.Error)
+					continue
+				}
+
+				now := time.Now().Format("2006-01-02 15:04:05")
+				prev, ok := previous[domainNS.Domain]
+				previous[domainNS.Domain] = nsSnapshot{RegistrarNS: domainNS.RegistrarNS, ZoneNS: domainNS.ZoneNS}
+
+				if !ok {
+					fmt.Printf("%s %s registrar NS: %s, zone NS: %s\n", now, domainNS.Domain, strings.Join(sortedSet(domainNS.RegistrarNS), " "), strings.Join(sortedSet(domainNS.ZoneNS), " "))
+					continue
+				}
+
+				if diff := nsDiff(prev.RegistrarNS, domainNS.RegistrarNS, colour); diff != "" {
+					fmt.Printf("%s %s registrar NS changed: %s\n", now, domainNS.Domain, diff)
+					recordChange(domainNS.Domain, "registrar", prev.RegistrarNS, domainNS.RegistrarNS)
+				}
+				if diff := nsDiff(prev.ZoneNS, domainNS.ZoneNS, colour); diff != "" {
+					fmt.Printf("%s %s zone NS changed: %s\n", now, domainNS.Domain, diff)
+					recordChange(domainNS.Domain, "zone", prev.ZoneNS, domainNS.ZoneNS)
+				}
+			}
+			domains.Close()
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+// recordChange appends the change to the --change-log, if set.
+func recordChange(domain, records string, before, after mapset.Set) {
+	if *argsChangeLog == "" {
+		return
+	}
+	if err := logChange(newNSChange(domain, records, before, after)); err != nil {
+		log.Println("Error writing change log:", err)
+	}
+}
+
+// nsDiff returns the name servers added (+) and removed (-) from prev, in green
+// and red if colour is set, or an empty string if they're the same.
+func nsDiff(prev, cur mapset.Set, colour bool) string {
+	var diff []string
+
+	for _, ns := range sortedSet(cur.Difference(prev)) {
+		if colour {
+			diff = append(diff, "\x1b[32m+"+ns+"\x1b[0m")
+		} else {
+			diff = append(diff, "+"+ns)
+		}
+	}
+
+	for _, ns := range sortedSet(prev.Difference(cur)) {
+		if colour {
+			diff = append(diff, "\x1b[31m-"+ns+"\x1b[0m")
+		} else {
+			diff = append(diff, "-"+ns)
+		}
+	}
+
+	return strings.Join(diff, " ")
+}
+
+// sortedSet returns the set's strings in order.
+func sortedSet(set mapset.Set) []string {
+	var s []string
+	for _, v := range set.ToSlice() {
+		s = append(s, v.(string))
+	}
+	sort.Strings(s)
+	return s
+}
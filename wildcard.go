@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/miekg/dns"
+)
+
+// wildcard is the answers given for a random label under the domain, which
+// shouldn't exist.
+type wildcard struct {
+	Name string `json:"name"`
+	// Zone is the answer from the zone's name server
+	Zone []string `json:"zone,omitempty"`
+	// Parent is the answer from the parent zone's name server, which should
+	// refer to the zone rather than answer
+	Parent []string `json:"parent,omitempty"`
+}
+
+// queryWildcard queries the zone and parent name servers for A records of a
+// random label under the domain.
+func queryWildcard(ctx context.Context, domain, parentNS, zoneNS string) (*wildcard, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	w := &wildcard{Name: "nsaudit-" + hex.EncodeToString(b) + "." + domain}
+
+	for _, server := range []struct {
+		ns      string
+		records *[]string
+	}{{zoneNS, &w.Zone}, {parentNS, &w.Parent}} {
+		r, err := query(ctx, w.Name, server.ns, dns.TypeA)
+		if err != nil {
+			return nil, err
+		}
+		if r.Rcode != dns.RcodeSuccess {
+			continue
+		}
+		for _, a := range r.Answer {
+			*server.records = append(*server.records, rdata(a))
+		}
+	}
+
+	return w, nil
+}
+
+func compareWildcard(domainNS *DomainNS) (errors int) {
+
+	if domainNS.Wildcard == nil {
+		return
+	}
+
+	if len(domainNS.Wildcard.Zone) > 0 {
+		domainNS.MSGs = append(domainNS.MSGs, msg{pri: LOG_ERR, check: checkWildcard, msg: fmt.Sprintf("Zone has a wildcard, %s answered with %v", domainNS.Wildcard.Name, domainNS.Wildcard.Zone)})
+		errors++
+	}
+
+	if len(domainNS.Wildcard.Parent) > 0 {
+		domainNS.MSGs = append(domainNS.MSGs, msg{pri: LOG_ERR, check: checkWildcard, msg: fmt.Sprintf("Parent zone synthesizes answers, %s answered with %v", domainNS.Wildcard.Name, domainNS.Wildcard.Parent)})
+		errors++
+	}
+
+	return
+}
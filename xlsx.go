@@ -0,0 +1,227 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// xlsxReader reads rows from a worksheet of an Excel .xlsx file, in the same
+// way as csv.Reader reads records.
+type xlsxReader struct {
+	rows [][]string
+}
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxRichText `xml:"si"`
+}
+
+type xlsxRichText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (rt xlsxRichText) String() string {
+	s := rt.T
+	for _, run := range rt.Runs {
+		s += run.T
+	}
+	return s
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		Cells []struct {
+			Ref    string       `xml:"r,attr"`
+			Type   string       `xml:"t,attr"`
+			Value  string       `xml:"v"`
+			Inline xlsxRichText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// newXLSXReader reads the worksheet from r, by name or number starting at 1,
+// or the first worksheet if sheet is empty.
+func newXLSXReader(r io.Reader, sheet string) (*xlsxReader, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var workbook xlsxWorkbook
+	if err := xlsxDecode(files, "xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+	if len(workbook.Sheets) == 0 {
+		return nil, errors.New("Workbook has no worksheets")
+	}
+
+	rid := ""
+	if sheet == "" {
+		rid = workbook.Sheets[0].RID
+	} else if n, err := strconv.Atoi(sheet); err == nil {
+		if n < 1 || n > len(workbook.Sheets) {
+			return nil, errors.New(fmt.Sprintf("Worksheet %d not found, workbook has %d worksheets", n, len(workbook.Sheets)))
+		}
+		rid = workbook.Sheets[n-1].RID
+	} else {
+		for _, s := range workbook.Sheets {
+			if strings.EqualFold(s.Name, sheet) {
+				rid = s.RID
+			}
+		}
+		if rid == "" {
+			return nil, errors.New(fmt.Sprintf("Worksheet %s not found", sheet))
+		}
+	}
+
+	var rels xlsxRelationships
+	if err := xlsxDecode(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+
+	name := ""
+	for _, rel := range rels.Relationships {
+		if rel.ID != rid {
+			continue
+		}
+		// Targets are relative to xl/, unless they're absolute
+		if strings.HasPrefix(rel.Target, "/") {
+			name = strings.TrimPrefix(rel.Target, "/")
+		} else {
+			name = path.Join("xl", rel.Target)
+		}
+	}
+	if name == "" {
+		return nil, errors.New(fmt.Sprintf("Worksheet relationship %s not found", rid))
+	}
+
+	// Workbooks without any text cells don't have shared strings
+	var sst xlsxSharedStrings
+	if _, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := xlsxDecode(files, "xl/sharedStrings.xml", &sst); err != nil {
+			return nil, err
+		}
+	}
+
+	var worksheet xlsxWorksheet
+	if err := xlsxDecode(files, name, &worksheet); err != nil {
+		return nil, err
+	}
+
+	x := &xlsxReader{}
+	for _, row := range worksheet.Rows {
+		var record []string
+		for i, cell := range row.Cells {
+			// Empty cells are usually omitted, so place cells by their reference
+			col := xlsxColumn(cell.Ref)
+			if col < 0 {
+				col = i
+			}
+			if col >= xlsxMaxColumns {
+				return nil, errors.New(fmt.Sprintf("Invalid cell reference %s, past the last column XFD", cell.Ref))
+			}
+			for len(record) <= col {
+				record = append(record, "")
+			}
+
+			switch cell.Type {
+			case "s":
+				n, err := strconv.Atoi(cell.Value)
+				if err != nil || n < 0 || n >= len(sst.Items) {
+					return nil, errors.New(fmt.Sprintf("Invalid shared string in cell %s", cell.Ref))
+				}
+				record[col] = sst.Items[n].String()
+			case "inlineStr":
+				record[col] = cell.Inline.String()
+			default:
+				record[col] = cell.Value
+			}
+		}
+		x.rows = append(x.rows, record)
+	}
+
+	return x, nil
+}
+
+// Read returns the next row, or io.EOF after the last row.
+func (x *xlsxReader) Read() ([]string, error) {
+	if len(x.rows) == 0 {
+		return nil, io.EOF
+	}
+	row := x.rows[0]
+	x.rows = x.rows[1:]
+	return row, nil
+}
+
+func xlsxDecode(files map[string]*zip.File, name string, v interface{}) error {
+	f, ok := files[name]
+	if !ok {
+		return errors.New(fmt.Sprintf("Invalid xlsx file, %s not found", name))
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	return xml.NewDecoder(rc).Decode(v)
+}
+
+// Columns in a worksheet, A to XFD
+const xlsxMaxColumns = 16384
+
+// xlsxColumn returns the zero based column of a cell reference such as AB12,
+// or -1 if ref is invalid. Columns past XFD return xlsxMaxColumns.
+func xlsxColumn(ref string) int {
+	col := 0
+	n := 0
+	for _, c := range strings.ToUpper(ref) {
+		if c < 'A' || c > 'Z' {
+			break
+		}
+		col = col*26 + int(c-'A'+1)
+		if col > xlsxMaxColumns {
+			return xlsxMaxColumns
+		}
+		n++
+	}
+	if n == 0 {
+		return -1
+	}
+	return col - 1
+}
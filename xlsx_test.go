@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestXLSXColumn(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want int
+	}{
+		{"A1", 0},
+		{"a1", 0},
+		{"Z9", 25},
+		{"AA1", 26},
+		{"AB12", 27},
+		{"XFD1", 16383},
+		{"XFE1", xlsxMaxColumns},
+		{"ZZZZZZZ1", xlsxMaxColumns},
+		{"12", -1},
+		{"", -1},
+	}
+
+	for _, tt := range tests {
+		if got := xlsxColumn(tt.ref); got != tt.want {
+			t.Errorf("xlsxColumn(%q) got %d, want %d", tt.ref, got, tt.want)
+		}
+	}
+}